@@ -13,6 +13,7 @@ import (
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
 	"github.com/aquasecurity/vexhub-crawler/pkg/vexhub"
 )
 
@@ -36,6 +37,12 @@ func run() error {
 	vexHubDir := flag.String("vexhub-dir", "", "Vex Hub directory")
 	strict := flag.Bool("strict", false, "Strict mode")
 	debug := flag.Bool("debug", false, "Enable debug logging")
+	gitCacheDir := flag.String("git-cache-dir", "", "Directory to keep mirrors of source repositories between runs")
+	gitCacheMaxSize := flag.Int64("git-cache-max-size", 10<<10, "Size of the git cache in MiB to start evicting least recently used mirrors, 0 for no limit")
+	maxRepoSize := flag.Int64("max-repo-size", vex.DefaultLimits.MaxRepoSize>>20, "Size of a source repository or an archive in MiB, 0 for no limit")
+	maxWalkDepth := flag.Int("max-walk-depth", vex.DefaultLimits.MaxDepth, "Depth of directories searched for VEX documents, 0 for no limit")
+	maxFiles := flag.Int("max-files", vex.DefaultLimits.MaxFiles, "Number of files searched for VEX documents, 0 for no limit")
+	maxDocumentSize := flag.Int64("max-document-size", vex.DefaultLimits.MaxDocumentSize>>20, "Size of a VEX document in MiB, 0 for no limit")
 	flag.Parse()
 
 	if *vexHubDir == "" {
@@ -55,7 +62,19 @@ func run() error {
 	if err = crawl.Packages(ctx, crawl.Options{
 		VEXHubDir: *vexHubDir,
 		Packages:  c.Packages,
+		Crawlers:  c.Crawlers,
 		Strict:    *strict,
+		Git:       c.Git,
+
+		GitCacheDir:     *gitCacheDir,
+		GitCacheMaxSize: *gitCacheMaxSize << 20,
+
+		Limits: vex.Limits{
+			MaxRepoSize:     *maxRepoSize << 20,
+			MaxDepth:        *maxWalkDepth,
+			MaxFiles:        *maxFiles,
+			MaxDocumentSize: *maxDocumentSize << 20,
+		},
 	}); err != nil {
 		return oops.Wrapf(err, "failed to crawl packages")
 	}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
@@ -15,6 +16,8 @@ type Package struct {
 
 type configFile struct {
 	Packages packages `yaml:"pkg"`
+	Crawlers Crawlers `yaml:"crawlers"`
+	Git      Git      `yaml:"git"`
 }
 
 type packages map[string][]struct {
@@ -31,6 +34,180 @@ type packages map[string][]struct {
 
 type Config struct {
 	Packages []Package
+	Crawlers Crawlers
+	Git      Git
+}
+
+// Git holds settings for fetching source repositories
+type Git struct {
+	// Credentials are matched against the hosts of repositories.
+	Credentials []GitCredential `yaml:"credentials"`
+	// KnownHosts is the path to known_hosts to verify SSH hosts with. Defaults to $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+	KnownHosts string `yaml:"known_hosts"`
+}
+
+// GitCredential holds credentials for a host. A token is used as the password for HTTP(S),
+// and the SSH key for SSH, falling back to the SSH agent.
+// Environment variables such as `${GIT_TOKEN}` are expanded.
+type GitCredential struct {
+	Host             string `yaml:"host"`
+	Username         string `yaml:"username"` // Defaults to "git"
+	Password         string `yaml:"password"`
+	Token            string `yaml:"token"` // Alias of password
+	SSHKey           string `yaml:"ssh_key"`
+	SSHKeyPassphrase string `yaml:"ssh_key_passphrase"`
+}
+
+// Crawlers holds ecosystem-specific settings for crawlers
+type Crawlers struct {
+	Golang   Golang   `yaml:"golang"`
+	Maven    Maven    `yaml:"maven"`
+	OCI      OCI      `yaml:"oci"`
+	RubyGems RubyGems `yaml:"rubygems"`
+	NuGet    NuGet    `yaml:"nuget"`
+	Composer Composer `yaml:"composer"`
+	Helm     Helm     `yaml:"helm"`
+	Deb      Deb      `yaml:"deb"`
+	RPM      RPM      `yaml:"rpm"`
+	APK      APK      `yaml:"apk"`
+	Conda    Conda    `yaml:"conda"`
+	// Plugins take precedence over the crawlers of the same types.
+	Plugins []Plugin `yaml:"plugins"`
+
+	// Others holds settings of third-party crawlers by their keys, to be decoded by their factories.
+	Others map[string]yaml.Node `yaml:",inline"`
+
+	// MaxDocumentSize limits VEX documents crawlers fetch by themselves, such as OCI artifacts.
+	// It's set from the command line rather than the config file. Zero means no limit.
+	MaxDocumentSize int64 `yaml:"-"`
+}
+
+// Golang holds settings for the Go crawler.
+// Empty values fall back to the environment variables of the same names.
+type Golang struct {
+	GOPROXY   string `yaml:"goproxy"`
+	GONOPROXY string `yaml:"gonoproxy"`
+}
+
+// Maven holds settings for the Maven crawler
+type Maven struct {
+	// Repositories are tried in order. Maven Central is used if empty.
+	Repositories []MavenRepository `yaml:"repositories"`
+	// Settings is the path to settings.xml to read server credentials from.
+	Settings string `yaml:"settings"`
+}
+
+// RubyGems holds settings for the RubyGems crawler
+type RubyGems struct {
+	// URL is the gem server compatible with the RubyGems.org API. Defaults to https://rubygems.org.
+	URL string `yaml:"url"`
+}
+
+// NuGet holds settings for the NuGet crawler.
+// Environment variables such as `${NUGET_API_KEY}` are expanded in credentials.
+type NuGet struct {
+	// URL is the service index of the feed. Defaults to https://api.nuget.org/v3/index.json.
+	URL    string `yaml:"url"`
+	APIKey string `yaml:"api_key"` // Sent in the X-NuGet-ApiKey header
+	// Username and Password are used for basic authentication, e.g. Azure Artifacts or GitHub Packages.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Composer holds settings for the Composer crawler.
+// Environment variables such as `${COMPOSER_TOKEN}` are expanded in credentials.
+type Composer struct {
+	// URL is the Composer repository such as Satis or Private Packagist. Defaults to https://repo.packagist.org.
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Helm holds settings for the Helm crawler
+type Helm struct {
+	// Repositories are referred to by the namespace of PURLs, e.g. pkg:helm/bitnami/nginx.
+	Repositories []HelmRepository `yaml:"repositories"`
+}
+
+// HelmRepository is an HTTP(S) chart repository, or an OCI registry with the "oci://" scheme.
+// Environment variables such as `${HELM_PASSWORD}` are expanded in credentials.
+type HelmRepository struct {
+	Name     string `yaml:"name"`
+	URL      string `yaml:"url"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Deb holds settings for the Debian crawler
+type Deb struct {
+	// Repositories are archives per namespace, which can be mirrors or local directories.
+	// Debian and Ubuntu archives are used for namespaces not configured.
+	Repositories []DebRepository `yaml:"repositories"`
+}
+
+type DebRepository struct {
+	Namespace  string   `yaml:"namespace"` // e.g. debian
+	URL        string   `yaml:"url"`
+	Suite      string   `yaml:"suite"`
+	Components []string `yaml:"components"`
+}
+
+// RPM holds settings for the RPM crawler
+type RPM struct {
+	// URL is the template of spec files where `{name}` is replaced. Defaults to Fedora's dist-git.
+	URL string `yaml:"url"`
+}
+
+// APK holds settings for the Alpine crawler
+type APK struct {
+	// URL is the aports tree, which can be a mirror or a local checkout.
+	URL string `yaml:"url"`
+	// Repositories are looked up in order. Defaults to main and community.
+	Repositories []string `yaml:"repositories"`
+}
+
+// Conda holds settings for the conda crawler
+type Conda struct {
+	// URL is the template of recipes where `{name}` is replaced. Defaults to conda-forge feedstocks.
+	URL string `yaml:"url"`
+}
+
+// Plugin is an external executable detecting source repositories for the package type.
+// The PURL is written to stdin as JSON, and the source URL is read from stdout as JSON.
+type Plugin struct {
+	Type    string        `yaml:"type"`
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"` // Defaults to 1 minute
+}
+
+// OCI holds settings for the OCI crawler
+type OCI struct {
+	// Platform is the platform to inspect in multi-platform images, e.g. linux/arm64. Defaults to linux/amd64.
+	Platform string `yaml:"platform"`
+	// Layout is the path to an OCI image layout to read images from instead of registries.
+	Layout string `yaml:"layout"`
+	// Credentials take precedence over the Docker config file and credential helpers.
+	Credentials []RegistryCredential `yaml:"credentials"`
+	// Mirrors maps a registry host to its mirrors, e.g. docker.io => [mirror.gcr.io].
+	Mirrors map[string][]string `yaml:"mirrors"`
+	// PublicKeys are paths to PEM-encoded public keys, such as cosign.pub, to verify attestations with.
+	// Attestations are not verified if empty.
+	PublicKeys []string `yaml:"public_keys"`
+}
+
+// RegistryCredential holds credentials for a registry.
+// Environment variables such as `${REGISTRY_PASSWORD}` are expanded.
+type RegistryCredential struct {
+	Registry string `yaml:"registry"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Token    string `yaml:"token"` // Bearer token used instead of username and password
+}
+
+type MavenRepository struct {
+	ID  string `yaml:"id"` // Matched against server IDs in settings.xml
+	URL string `yaml:"url"`
 }
 
 func Load(configPath string) (*Config, error) {
@@ -53,6 +230,8 @@ func Load(configPath string) (*Config, error) {
 
 	return &Config{
 		Packages: pkgs,
+		Crawlers: config.Crawlers,
+		Git:      config.Git,
 	}, nil
 }
 
@@ -0,0 +1,174 @@
+package apk
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const aportsURL = "https://gitlab.alpinelinux.org/alpine/aports/-/raw/master"
+
+var (
+	defaultRepositories = []string{"main", "community"}
+
+	// varRegex matches `${var}` and `$var`. Parameter expansions such as `${var//./_}` are not supported.
+	varRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+)
+
+type Crawler struct {
+	url   string
+	repos []string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the aports tree, which can be a mirror or a local checkout.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+// WithRepositories sets the repositories of aports to look up in order, e.g. main and community.
+func WithRepositories(repos ...string) Option {
+	return func(c *Crawler) {
+		if len(repos) > 0 {
+			c.repos = repos
+		}
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url:   aportsURL,
+		repos: defaultRepositories,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("apk").With("purl", pkg.PURL.String())
+	// "apk" type uses namespace for the vendor, and the name must be the origin package having APKBUILD
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#apk
+	vars, err := c.fetchAPKBUILD(ctx, pkg.PURL.Name)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get APKBUILD")
+	}
+
+	// `url` is often the project website, so the repository hosting `source` archives is preferred
+	// cf. https://wiki.alpinelinux.org/wiki/APKBUILD_Reference
+	if u, ok := xurl.ParseForge(vars["url"]); ok {
+		return u, nil
+	}
+	for _, s := range strings.Fields(vars["source"]) {
+		// Sources can be renamed, e.g. `<filename>::<url>`
+		if _, after, found := strings.Cut(s, "::"); found {
+			s = after
+		}
+		if u, ok := xurl.ParseForge(s); ok {
+			return u, nil
+		}
+	}
+	if vars["url"] == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(vars["url"])
+	if err != nil {
+		return nil, errBuilder.With("src", vars["url"]).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// fetchAPKBUILD returns the variables of APKBUILD found first in the repositories.
+func (c *Crawler) fetchAPKBUILD(ctx context.Context, name string) (map[string]string, error) {
+	var errs []error
+	for _, repo := range c.repos {
+		// e.g. https://gitlab.alpinelinux.org/alpine/aports/-/raw/master/main/curl/APKBUILD
+		apkbuildURL, err := url.JoinPath(c.url, repo, name, "APKBUILD")
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to build APKBUILD url")
+		}
+		r, err := download.Open(ctx, apkbuildURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		defer r.Close()
+
+		vars, err := parseAPKBUILD(r)
+		if err != nil {
+			return nil, oops.With("url", apkbuildURL).Wrapf(err, "failed to parse APKBUILD")
+		}
+		return vars, nil
+	}
+	return nil, errors.Join(errs...)
+}
+
+// parseAPKBUILD extracts top-level variable assignments from APKBUILD, which is a shell script.
+// Quoted values may span multiple lines, and variables defined earlier are expanded.
+func parseAPKBUILD(r io.Reader) (map[string]string, error) {
+	vars := make(map[string]string)
+	var name, value string
+	var quote byte
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if quote != 0 {
+			// Continuation of a quoted value
+			if i := strings.IndexByte(line, quote); i >= 0 {
+				vars[name] = expand(value+"\n"+line[:i], vars)
+				quote = 0
+			} else {
+				value += "\n" + line
+			}
+			continue
+		}
+
+		key, v, found := strings.Cut(line, "=")
+		if !found || key == "" || strings.ContainsAny(key, " \t$()") {
+			continue
+		}
+		if v != "" && (v[0] == '"' || v[0] == '\'') {
+			q := v[0]
+			if i := strings.IndexByte(v[1:], q); i >= 0 {
+				v = v[1 : i+1]
+			} else {
+				name, value, quote = key, v[1:], q
+				continue
+			}
+		} else if i := strings.IndexAny(v, " \t#"); i >= 0 {
+			v = v[:i]
+		}
+		vars[key] = expand(v, vars)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oops.Wrap(err)
+	}
+	return vars, nil
+}
+
+func expand(s string, vars map[string]string) string {
+	return varRegex.ReplaceAllStringFunc(s, func(m string) string {
+		sub := varRegex.FindStringSubmatch(m)
+		if v, ok := vars[sub[1]+sub[2]]; ok {
+			return v
+		}
+		return m
+	})
+}
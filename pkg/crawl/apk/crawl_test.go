@@ -0,0 +1,102 @@
+package apk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/apk"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		url     string // Defaults to the test server
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with source",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeApk,
+					Namespace: "alpine",
+					Name:      "openssl",
+				},
+			},
+			want: "https://github.com/openssl/openssl",
+		},
+		{
+			name: "happy path with renamed source in community",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeApk,
+					Namespace: "alpine",
+					Name:      "jq",
+				},
+			},
+			want: "https://codeberg.org/mirrors/jq",
+		},
+		{
+			name: "happy path with url in local aports",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeApk,
+					Namespace: "alpine",
+					Name:      "yq-go",
+				},
+			},
+			url:  "testdata",
+			want: "https://github.com/mikefarah/yq",
+		},
+		{
+			name: "sad path without url and source",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeApk,
+					Namespace: "alpine",
+					Name:      "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeApk,
+					Namespace: "alpine",
+					Name:      "missed",
+				},
+			},
+			wantErr: "failed to get: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.url
+			if u == "" {
+				u = ts.URL
+			}
+			crawler := apk.NewCrawler(apk.WithURL(u))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
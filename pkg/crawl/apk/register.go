@@ -0,0 +1,17 @@
+package apk
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeApk, func(conf config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(
+			WithURL(conf.APK.URL),
+			WithRepositories(conf.APK.Repositories...),
+		), nil
+	})
+}
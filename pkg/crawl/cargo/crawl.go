@@ -1,18 +1,38 @@
 package cargo
 
 import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/samber/oops"
+	"golang.org/x/mod/semver"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
-const cratesAPI = "https://crates.io/api/v1/crates/"
+const (
+	cratesAPI   = "https://crates.io/api/v1/crates/"
+	cratesIndex = "https://index.crates.io/"
+
+	// Need to set a user-agent header
+	// cf. https://crates.io/data-access
+	userAgent = "aquasecurity/vex-crawler"
+
+	// maxCrateSize limits the size of .crate files to download
+	maxCrateSize = 50 << 20
+)
 
 type Response struct {
 	Crate struct {
@@ -20,21 +40,54 @@ type Response struct {
 	} `json:"crate"`
 }
 
+// IndexConfig represents config.json of a registry index
+// cf. https://doc.rust-lang.org/cargo/reference/registry-index.html#index-configuration
+type IndexConfig struct {
+	DL  string `json:"dl"`
+	API string `json:"api"`
+}
+
+// IndexEntry represents a line of an index file
+// cf. https://doc.rust-lang.org/cargo/reference/registry-index.html#json-schema
+type IndexEntry struct {
+	Name     string `json:"name"`
+	Version  string `json:"vers"`
+	Checksum string `json:"cksum"`
+	Yanked   bool   `json:"yanked"`
+}
+
+// Manifest represents Cargo.toml
+type Manifest struct {
+	Package struct {
+		Repository string `toml:"repository"`
+	} `toml:"package"`
+}
+
 type Crawler struct {
-	url string
+	url      string
+	indexURL string
 }
 
 type Option func(*Crawler)
 
+// WithURL sets the crates.io-compatible API URL.
 func WithURL(url string) Option {
 	return func(c *Crawler) {
 		c.url = url
 	}
 }
 
+// WithIndexURL sets the sparse index URL used when `repository_url` is not specified.
+func WithIndexURL(url string) Option {
+	return func(c *Crawler) {
+		c.indexURL = url
+	}
+}
+
 func NewCrawler(opts ...Option) *Crawler {
 	crawler := &Crawler{
-		url: cratesAPI,
+		url:      cratesAPI,
+		indexURL: cratesIndex,
 	}
 	for _, opt := range opts {
 		opt(crawler)
@@ -42,47 +95,302 @@ func NewCrawler(opts ...Option) *Crawler {
 	return crawler
 }
 
+// DetectSrc detects the source repository URL of the crate.
+// It queries the registry API first, and then falls back to `package.repository` in Cargo.toml
+// of the latest .crate file found via the sparse index.
+// For alternate registries, the `repository_url` qualifier must point to the sparse index.
 func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
 	errBuilder := oops.Code("crawl_error").In("cargo").With("purl", pkg.PURL.String())
+
+	repo, err := c.detectRepository(ctx, pkg)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+
+	u, err := xurl.Parse(repo)
+	if err != nil {
+		return nil, errBuilder.With("url", repo).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+func (c *Crawler) detectRepository(ctx context.Context, pkg config.Package) (string, error) {
 	// Cargo doesn't use `namespace`
 	// cf. https://github.com/package-url/purl-spec/blob/master/PURL-TYPES.rst#cargo
-	rawurl, err := url.JoinPath(c.url, pkg.PURL.Name)
-	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to build url")
+	name := pkg.PURL.Name
+
+	apiURL, indexURL := c.url, c.indexURL
+	var indexConfig *IndexConfig
+	if v, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		indexURL = strings.TrimPrefix(v, "sparse+")
+
+		var err error
+		if indexConfig, err = c.fetchIndexConfig(ctx, indexURL); err != nil {
+			return "", oops.Wrapf(err, "failed to fetch index config")
+		}
+
+		// The API is optional for alternate registries
+		apiURL = ""
+		if indexConfig.API != "" {
+			if apiURL, err = url.JoinPath(indexConfig.API, "api", "v1", "crates"); err != nil {
+				return "", oops.Wrapf(err, "failed to build API url")
+			}
+		}
 	}
 
-	errBuilder = errBuilder.With("url", rawurl)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	var errs []error
+	if apiURL != "" {
+		repo, err := c.fetchRepositoryFromAPI(ctx, apiURL, name)
+		if err == nil {
+			return repo, nil
+		}
+		errs = append(errs, err)
+		slog.Info("Falling back to the crate file", slog.String("purl", pkg.PURL.String()),
+			slog.String("error", err.Error()))
+	}
+
+	if indexConfig == nil {
+		var err error
+		if indexConfig, err = c.fetchIndexConfig(ctx, indexURL); err != nil {
+			errs = append(errs, oops.Wrapf(err, "failed to fetch index config"))
+			return "", errors.Join(errs...)
+		}
+	}
+
+	repo, err := c.fetchRepositoryFromCrate(ctx, indexURL, indexConfig, name)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to create request")
+		errs = append(errs, err)
+		return "", errors.Join(errs...)
 	}
+	return repo, nil
+}
 
-	// Need to set a user-agent header
-	// cf. https://crates.io/data-access
-	req.Header.Set("User-Agent", "aquasecurity/vex-crawler")
+// fetchRepositoryFromAPI queries the crates.io-compatible web API.
+func (c *Crawler) fetchRepositoryFromAPI(ctx context.Context, apiURL, name string) (string, error) {
+	rawurl, err := url.JoinPath(apiURL, name)
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to build url")
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	errBuilder := oops.With("url", rawurl)
+	resp, err := c.get(ctx, rawurl)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to get package info")
+		return "", errBuilder.Wrapf(err, "failed to get package info")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return nil, errBuilder.Errorf("failed to get package info: %s", resp.Status)
+		return "", errBuilder.Errorf("failed to get package info: %s", resp.Status)
 	}
 
 	var r Response
 	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to decode response")
+		return "", errBuilder.Wrapf(err, "failed to decode response")
 	}
 
 	if r.Crate.Repository == "" {
-		return nil, errBuilder.Errorf("no repository URL found")
+		return "", errBuilder.Errorf("no repository URL found")
 	}
+	return r.Crate.Repository, nil
+}
 
-	u, err := xurl.Parse(r.Crate.Repository)
+// fetchIndexConfig fetches config.json of the sparse index.
+func (c *Crawler) fetchIndexConfig(ctx context.Context, indexURL string) (*IndexConfig, error) {
+	rawurl, err := url.JoinPath(indexURL, "config.json")
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to normalize URL")
+		return nil, oops.Wrapf(err, "failed to build url")
 	}
-	return u, nil
+
+	errBuilder := oops.With("url", rawurl)
+	resp, err := c.get(ctx, rawurl)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get index config")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get index config: %s", resp.Status)
+	}
+
+	var cfg IndexConfig
+	if err = json.NewDecoder(resp.Body).Decode(&cfg); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode index config")
+	}
+	if cfg.DL == "" {
+		return nil, errBuilder.Errorf("no download URL in index config")
+	}
+	return &cfg, nil
+}
+
+// fetchRepositoryFromCrate downloads the latest .crate file and reads `package.repository` from Cargo.toml.
+func (c *Crawler) fetchRepositoryFromCrate(ctx context.Context, indexURL string, cfg *IndexConfig, name string) (string, error) {
+	entry, err := c.fetchLatestEntry(ctx, indexURL, name)
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to find the latest version")
+	}
+
+	rawurl := downloadURL(cfg.DL, entry)
+	errBuilder := oops.With("url", rawurl).With("version", entry.Version)
+	resp, err := c.get(ctx, rawurl)
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to download crate")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errBuilder.Errorf("failed to download crate: %s", resp.Status)
+	}
+
+	m, err := readManifest(io.LimitReader(resp.Body, maxCrateSize), entry)
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to read Cargo.toml")
+	}
+
+	if m.Package.Repository == "" {
+		return "", errBuilder.Errorf("no repository URL found in Cargo.toml")
+	}
+	return m.Package.Repository, nil
+}
+
+// fetchLatestEntry returns the highest non-yanked version in the index file.
+// Stable versions take precedence over prereleases.
+func (c *Crawler) fetchLatestEntry(ctx context.Context, indexURL, name string) (*IndexEntry, error) {
+	rawurl, err := url.JoinPath(indexURL, indexPath(name))
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build url")
+	}
+
+	errBuilder := oops.With("url", rawurl)
+	resp, err := c.get(ctx, rawurl)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get index file")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get index file: %s", resp.Status)
+	}
+
+	// Versions are appended in the order of publication, so backports can follow newer versions
+	var latest *IndexEntry
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry IndexEntry
+		if err = json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to decode index file")
+		}
+		if !entry.Yanked && newer(&entry, latest) {
+			latest = &entry
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to read index file")
+	}
+
+	if latest == nil {
+		return nil, errBuilder.Errorf("no available version found")
+	}
+	return latest, nil
+}
+
+// newer reports whether the entry is preferred over the current latest entry.
+// Versions that are not semver are ignored.
+func newer(entry, latest *IndexEntry) bool {
+	v := "v" + entry.Version
+	if !semver.IsValid(v) {
+		return false
+	} else if latest == nil {
+		return true
+	}
+	l := "v" + latest.Version
+	if stable, latestStable := semver.Prerelease(v) == "", semver.Prerelease(l) == ""; stable != latestStable {
+		return stable
+	}
+	return semver.Compare(v, l) > 0
+}
+
+func (c *Crawler) get(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create request")
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return http.DefaultClient.Do(req)
+}
+
+// readManifest extracts Cargo.toml from the .crate file (tar.gz).
+func readManifest(r io.Reader, entry *IndexEntry) (*Manifest, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to open gzip")
+	}
+	defer gr.Close()
+
+	// e.g. typemap-0.3.3/Cargo.toml
+	want := path.Join(entry.Name+"-"+entry.Version, "Cargo.toml")
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, oops.With("path", want).Errorf("Cargo.toml not found")
+		} else if err != nil {
+			return nil, oops.Wrapf(err, "failed to read tar")
+		}
+		if hdr.Typeflag != tar.TypeReg || path.Clean(hdr.Name) != want {
+			continue
+		}
+
+		var m Manifest
+		if _, err = toml.NewDecoder(tr).Decode(&m); err != nil {
+			return nil, oops.Wrapf(err, "failed to decode Cargo.toml")
+		}
+		return &m, nil
+	}
+}
+
+// indexPath returns the path of the index file for the crate
+// cf. https://doc.rust-lang.org/cargo/reference/registry-index.html#index-files
+func indexPath(name string) string {
+	name = strings.ToLower(name)
+	return path.Join(prefix(name), name)
+}
+
+func prefix(name string) string {
+	switch len(name) {
+	case 1:
+		return "1"
+	case 2:
+		return "2"
+	case 3:
+		return path.Join("3", name[:1])
+	default:
+		return path.Join(name[:2], name[2:4])
+	}
+}
+
+// downloadURL builds the .crate download URL from the `dl` template.
+// cf. https://doc.rust-lang.org/cargo/reference/registry-index.html#index-configuration
+func downloadURL(dl string, entry *IndexEntry) string {
+	markers := []string{"{crate}", "{version}", "{prefix}", "{lowerprefix}", "{sha256-checksum}"}
+	if !containsAny(dl, markers) {
+		return strings.TrimSuffix(dl, "/") + "/" + entry.Name + "/" + entry.Version + "/download"
+	}
+	r := strings.NewReplacer(
+		"{crate}", entry.Name,
+		"{version}", entry.Version,
+		"{prefix}", prefix(entry.Name),
+		"{lowerprefix}", prefix(strings.ToLower(entry.Name)),
+		"{sha256-checksum}", entry.Checksum,
+	)
+	return r.Replace(dl)
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
 }
@@ -1,11 +1,13 @@
 package cargo_test
 
 import (
+	"bytes"
 	"context"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/package-url/packageurl-go"
@@ -32,6 +34,58 @@ func TestCrawler_DetectSrc(t *testing.T) {
 			},
 			want: "https://github.com/reem/rust-typemap",
 		},
+		{
+			name: "happy path with crate file",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCargo,
+					Name: "fallback",
+				},
+			},
+			want: "https://github.com/example/fallback",
+		},
+		{
+			name: "happy path with alternate registry",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCargo,
+					Name: "typemap",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "sparse+{{server}}/alt/",
+						},
+					},
+				},
+			},
+			want: "https://github.com/reem/rust-typemap",
+		},
+		{
+			name: "sad path with only yanked versions",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCargo,
+					Name: "yanked",
+				},
+			},
+			wantErr: "no available version found",
+		},
+		{
+			name: "sad path with missing alternate registry",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCargo,
+					Name: "typemap",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "{{server}}/missing/",
+						},
+					},
+				},
+			},
+			wantErr: "failed to get index config: 404 Not Found",
+		},
 		{
 			name: "sad path with empty `repository` field",
 			pkg: config.Package{
@@ -67,7 +121,8 @@ func TestCrawler_DetectSrc(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(
 			tt.name, func(t *testing.T) {
-				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ts *httptest.Server
+				ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 					require.Equal(t, "aquasecurity/vex-crawler", r.Header.Get("User-Agent"))
 
 					f, err := os.ReadFile(filepath.Join("testdata", r.RequestURI))
@@ -75,12 +130,18 @@ func TestCrawler_DetectSrc(t *testing.T) {
 						w.WriteHeader(http.StatusNotFound)
 					}
 
+					// Index configs need to point to the test server
+					f = bytes.ReplaceAll(f, []byte("{{server}}"), []byte(ts.URL))
 					_, err = w.Write(f)
 					require.NoError(t, err)
 				}))
 				t.Cleanup(ts.Close)
 
-				crawler := cargo.NewCrawler(cargo.WithURL(ts.URL))
+				for i, q := range tt.pkg.PURL.Qualifiers {
+					tt.pkg.PURL.Qualifiers[i].Value = strings.ReplaceAll(q.Value, "{{server}}", ts.URL)
+				}
+
+				crawler := cargo.NewCrawler(cargo.WithURL(ts.URL), cargo.WithIndexURL(ts.URL+"/index/"))
 				got, err := crawler.DetectSrc(context.Background(), tt.pkg)
 				if tt.wantErr != "" {
 					require.ErrorContains(t, err, tt.wantErr)
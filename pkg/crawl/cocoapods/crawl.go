@@ -0,0 +1,194 @@
+package cocoapods
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const cdnURL = "https://cdn.cocoapods.org"
+
+// Podspec represents the podspec JSON in the Specs repository
+// cf. https://guides.cocoapods.org/syntax/podspec.html#source
+type Podspec struct {
+	Name   string `json:"name"`
+	Source struct {
+		Git string `json:"git"`
+		Tag string `json:"tag"`
+	} `json:"source"`
+	Homepage string `json:"homepage"`
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the CDN serving the trunk Specs repository.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		c.url = url
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: cdnURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("cocoapods").With("purl", pkg.PURL.String())
+	// "cocoapods" type doesn't have namespace, and subspecs are in subpath
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#cocoapods
+	name := pkg.PURL.Name
+	shard := shardPrefix(name)
+
+	version, err := c.latestVersion(ctx, name, shard)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get the latest version")
+	}
+
+	// Default url format is `https://cdn.cocoapods.org/Specs/<shard>/<name>/<version>/<name>.podspec.json`
+	specURL, err := url.JoinPath(c.url, append(append([]string{"Specs"}, shard...), name, version, name+".podspec.json")...)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build podspec url")
+	}
+
+	errBuilder = errBuilder.With("url", specURL).With("version", version)
+	resp, err := c.get(ctx, specURL)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get podspec")
+	}
+	defer resp.Body.Close()
+
+	var spec Podspec
+	if err = json.NewDecoder(resp.Body).Decode(&spec); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode podspec")
+	}
+
+	// Pods distributed as archives via `source.http` don't have the repository
+	if spec.Source.Git == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(spec.Source.Git)
+	if err != nil {
+		return nil, errBuilder.With("src", spec.Source.Git).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// latestVersion returns the latest version in the version index of the shard, preferring stable versions.
+// Each line of the index is `<name>/<version1>/<version2>/...`.
+// cf. https://blog.cocoapods.org/CocoaPods-1.7.2/
+func (c *Crawler) latestVersion(ctx context.Context, name string, shard []string) (string, error) {
+	indexURL, err := url.JoinPath(c.url, "all_pods_versions_"+strings.Join(shard, "_")+".txt")
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to build version index url")
+	}
+
+	errBuilder := oops.With("url", indexURL)
+	resp, err := c.get(ctx, indexURL)
+	if err != nil {
+		return "", errBuilder.Wrapf(err, "failed to get version index")
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "/")
+		if fields[0] != name {
+			continue
+		}
+		var latest, prerelease string
+		for _, v := range fields[1:] {
+			if strings.Contains(v, "-") {
+				if prerelease == "" || compareVersions(v, prerelease) > 0 {
+					prerelease = v
+				}
+			} else if latest == "" || compareVersions(v, latest) > 0 {
+				latest = v
+			}
+		}
+		if latest == "" {
+			latest = prerelease
+		}
+		if latest == "" {
+			return "", errBuilder.Errorf("no version found")
+		}
+		return latest, nil
+	}
+	if err = scanner.Err(); err != nil {
+		return "", errBuilder.Wrapf(err, "failed to read version index")
+	}
+	return "", errBuilder.Errorf("pod not found")
+}
+
+func (c *Crawler) get(ctx context.Context, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, oops.Wrap(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, oops.Errorf("%s", resp.Status)
+	}
+	return resp, nil
+}
+
+// shardPrefix returns the directories of the pod in the Specs repository,
+// which are the first three characters of the MD5 hash of the name.
+// cf. https://github.com/CocoaPods/Specs/blob/master/CocoaPods-version.yml
+func shardPrefix(name string) []string {
+	sum := md5.Sum([]byte(name))
+	h := hex.EncodeToString(sum[:])
+	return []string{h[0:1], h[1:2], h[2:3]}
+}
+
+// compareVersions compares dot-separated versions segment by segment, numerically if possible.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil && xn != yn:
+			if xn > yn {
+				return 1
+			}
+			return -1
+		case (xerr != nil || yerr != nil) && x != y:
+			return strings.Compare(x, y)
+		}
+	}
+	return 0
+}
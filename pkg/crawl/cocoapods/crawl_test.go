@@ -0,0 +1,81 @@
+package cocoapods_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/cocoapods"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCocoapods,
+					Name: "Alamofire",
+				},
+			},
+			want: "https://github.com/Alamofire/Alamofire.git",
+		},
+		{
+			name: "sad path with pod distributed as archive",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCocoapods,
+					Name: "NoURL",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with pod missing in version index",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCocoapods,
+					Name: "Missed",
+				},
+			},
+			wantErr: "pod not found",
+		},
+		{
+			name: "sad path with missed shard",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeCocoapods,
+					Name: "Unknown",
+				},
+			},
+			wantErr: "failed to get version index: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+			t.Cleanup(ts.Close)
+
+			crawler := cocoapods.NewCrawler(cocoapods.WithURL(ts.URL))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package cocoapods
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeCocoapods, func(config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(), nil
+	})
+}
@@ -0,0 +1,291 @@
+package composer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/samber/oops"
+	"golang.org/x/mod/semver"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const packagistURL = "https://repo.packagist.org"
+
+// Repository represents packages.json of a Composer repository
+// cf. https://getcomposer.org/doc/05-repositories.md#packages
+type Repository struct {
+	// MetadataURL is the template of package metadata URLs, e.g. /p2/%package%.json
+	MetadataURL string `json:"metadata-url"`
+	// Packages are inlined by small repositories such as Satis without metadata-url
+	Packages Packages       `json:"packages"`
+	Includes map[string]any `json:"includes"`
+}
+
+// Metadata represents the package metadata in the Composer v2 format
+type Metadata struct {
+	Packages Packages `json:"packages"`
+	Minified string   `json:"minified"` // "composer/2.0" if versions are minified
+}
+
+// Packages maps package names to their versions
+type Packages map[string]json.RawMessage
+
+// UnmarshalJSON accepts `[]` as well since PHP encodes empty maps as arrays.
+func (p *Packages) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("[]")) {
+		return nil
+	}
+	return json.Unmarshal(b, (*map[string]json.RawMessage)(p))
+}
+
+// Version represents a version of the package
+type Version struct {
+	Version string `json:"version"`
+	Source  struct {
+		URL string `json:"url"`
+	} `json:"source"`
+	Support struct {
+		Source string `json:"source"`
+	} `json:"support"`
+}
+
+type Crawler struct {
+	url      string
+	username string
+	password string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the Composer repository, e.g. Satis or Private Packagist.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+// WithBasicAuth sets credentials for the repository, e.g. the username and token of Private Packagist.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Crawler) {
+		c.username = username
+		c.password = password
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: packagistURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("composer").With("purl", pkg.PURL.String())
+	// Composer package names are lowercase "vendor/name"
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#composer
+	name := strings.ToLower(path.Join(pkg.PURL.Namespace, pkg.PURL.Name))
+	baseURL := c.url
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		baseURL = repositoryURL
+	}
+	errBuilder = errBuilder.With("repository", baseURL).With("package", name)
+
+	versions, err := c.versions(ctx, baseURL, name)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get package metadata")
+	}
+	v, err := latestVersion(versions)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get the latest version")
+	}
+
+	src := v.Source.URL
+	if src == "" {
+		src = v.Support.Source
+	}
+	if src == "" {
+		return nil, errBuilder.With("version", v.Version).Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// versions returns versions of the package through metadata-url, or from packages.json if inlined.
+func (c *Crawler) versions(ctx context.Context, baseURL, name string) ([]Version, error) {
+	repoURL, err := url.JoinPath(baseURL, "packages.json")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build repository url")
+	}
+	var repo Repository
+	if err = c.get(ctx, repoURL, &repo); err != nil {
+		return nil, oops.Wrapf(err, "failed to get packages.json")
+	}
+
+	if repo.MetadataURL == "" {
+		if raw, ok := repo.Packages[name]; ok {
+			return decodeVersions(raw, false)
+		}
+		// Satis splits packages into include files
+		for include := range repo.Includes {
+			includeURL, err := resolveURL(repoURL, include)
+			if err != nil {
+				return nil, err
+			}
+			var m Metadata
+			if err = c.get(ctx, includeURL, &m); err != nil {
+				return nil, oops.Wrapf(err, "failed to get included file")
+			}
+			if raw, ok := m.Packages[name]; ok {
+				return decodeVersions(raw, m.Minified != "")
+			}
+		}
+		return nil, oops.Errorf("package not found")
+	}
+
+	metadataURL, err := resolveURL(repoURL, strings.ReplaceAll(repo.MetadataURL, "%package%", name))
+	if err != nil {
+		return nil, err
+	}
+	var m Metadata
+	if err = c.get(ctx, metadataURL, &m); err != nil {
+		return nil, err
+	}
+	raw, ok := m.Packages[name]
+	if !ok {
+		return nil, oops.With("url", metadataURL).Errorf("package not found")
+	}
+	return decodeVersions(raw, m.Minified != "")
+}
+
+// decodeVersions decodes either a list of versions (v2) or a map from versions (v1).
+// Minified lists are expanded first, as they contain only changes from the previous version except for the first one.
+func decodeVersions(raw json.RawMessage, minified bool) ([]Version, error) {
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err == nil {
+		if minified {
+			entries = expand(entries)
+		}
+		var list []Version
+		for _, entry := range entries {
+			b, err := json.Marshal(entry)
+			if err != nil {
+				return nil, oops.Wrapf(err, "failed to encode version")
+			}
+			var v Version
+			if err = json.Unmarshal(b, &v); err != nil {
+				return nil, oops.Wrapf(err, "failed to decode version")
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+
+	var list []Version
+	var m map[string]Version
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode versions")
+	}
+	for _, v := range m {
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// expand restores minified versions, where each version inherits the keys of the previous one
+// and "__unset" removes a key.
+// cf. https://github.com/composer/metadata-minifier
+func expand(entries []map[string]json.RawMessage) []map[string]json.RawMessage {
+	unset, _ := json.Marshal("__unset")
+	expanded := make([]map[string]json.RawMessage, 0, len(entries))
+	prev := map[string]json.RawMessage{}
+	for _, entry := range entries {
+		version := maps.Clone(prev)
+		for k, v := range entry {
+			if bytes.Equal(bytes.TrimSpace(v), unset) {
+				delete(version, k)
+			} else {
+				version[k] = v
+			}
+		}
+		expanded = append(expanded, version)
+		prev = version
+	}
+	return expanded
+}
+
+// latestVersion returns the highest stable version, or any version if there are only development versions.
+func latestVersion(versions []Version) (*Version, error) {
+	if len(versions) == 0 {
+		return nil, oops.Errorf("no version found")
+	}
+	var latest *Version
+	for i, v := range versions {
+		sv := "v" + strings.TrimPrefix(v.Version, "v")
+		if !semver.IsValid(sv) || semver.Prerelease(sv) != "" {
+			continue
+		}
+		if latest == nil || semver.Compare(sv, "v"+strings.TrimPrefix(latest.Version, "v")) > 0 {
+			latest = &versions[i]
+		}
+	}
+	if latest == nil {
+		latest = &versions[0]
+	}
+	return latest, nil
+}
+
+// get fetches the JSON document. Credentials are sent only to the host of the configured repository.
+func (c *Crawler) get(ctx context.Context, rawurl string, v any) error {
+	errBuilder := oops.With("url", rawurl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to create request")
+	}
+	if repo, err := url.Parse(c.url); err == nil && repo.Host == req.URL.Host && (c.username != "" || c.password != "") {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to get package metadata")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errBuilder.Errorf("failed to get package metadata: %s", resp.Status)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errBuilder.Wrapf(err, "failed to decode response")
+	}
+	return nil
+}
+
+// resolveURL resolves the reference in packages.json, which can be relative to the repository.
+func resolveURL(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", oops.With("url", base).Wrapf(err, "failed to parse url")
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", oops.With("url", ref).Wrapf(err, "failed to parse url")
+	}
+	return b.ResolveReference(r).String(), nil
+}
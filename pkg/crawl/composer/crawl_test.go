@@ -0,0 +1,166 @@
+package composer_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/composer"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		repo    string // Path to the repository on the test server
+		opts    []composer.Option
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "laravel",
+					Name:      "framework",
+				},
+			},
+			want: "https://github.com/laravel/framework.git",
+		},
+		{
+			name: "happy path with support source",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "example",
+					Name:      "support-only",
+				},
+			},
+			want: "https://github.com/example/support-only",
+		},
+		{
+			name: "happy path with a prerelease as the newest minified version",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "example",
+					Name:      "prerelease",
+				},
+			},
+			want: "https://github.com/example/prerelease.git",
+		},
+		{
+			name: "happy path with Satis inlining packages",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "acme",
+					Name:      "inline",
+				},
+			},
+			repo: "/satis/",
+			want: "https://git.example.com/acme/inline.git",
+		},
+		{
+			name: "happy path with Satis includes",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "acme",
+					Name:      "included",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "{{server}}/satis-includes/",
+						},
+					},
+				},
+			},
+			want: "https://git.example.com/acme/included.git",
+		},
+		{
+			name: "happy path with private repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "acme",
+					Name:      "private",
+				},
+			},
+			repo: "/private/",
+			opts: []composer.Option{composer.WithBasicAuth("token", "secret")},
+			want: "https://github.com/acme/private.git",
+		},
+		{
+			name: "sad path with private repository without credentials",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "acme",
+					Name:      "private",
+				},
+			},
+			repo:    "/private/",
+			wantErr: "failed to get package metadata: 401 Unauthorized",
+		},
+		{
+			name: "sad path when metadata doesn't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "example",
+					Name:      "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeComposer,
+					Namespace: "example",
+					Name:      "missed",
+				},
+			},
+			wantErr: "failed to get package metadata: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := http.FileServer(http.Dir("testdata"))
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, pass, ok := r.BasicAuth(); strings.HasPrefix(r.URL.Path, "/private/") &&
+					(!ok || user != "token" || pass != "secret") {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fs.ServeHTTP(w, r)
+			}))
+			t.Cleanup(ts.Close)
+
+			for i, q := range tt.pkg.PURL.Qualifiers {
+				tt.pkg.PURL.Qualifiers[i].Value = strings.ReplaceAll(q.Value, "{{server}}", ts.URL)
+			}
+			opts := append([]composer.Option{composer.WithURL(ts.URL + tt.repo)}, tt.opts...)
+
+			crawler := composer.NewCrawler(opts...)
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
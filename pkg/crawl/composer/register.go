@@ -0,0 +1,24 @@
+package composer
+
+import (
+	"os"
+
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeComposer, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.Composer), nil
+	})
+}
+
+func newFromConfig(conf config.Composer) *Crawler {
+	opts := []Option{WithURL(conf.URL)}
+	if conf.Username != "" || conf.Password != "" {
+		opts = append(opts, WithBasicAuth(os.ExpandEnv(conf.Username), os.ExpandEnv(conf.Password)))
+	}
+	return NewCrawler(opts...)
+}
@@ -0,0 +1,167 @@
+package conda
+
+import (
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/samber/oops"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// feedstockURL is the URL template of recipes in conda-forge feedstocks
+const feedstockURL = "https://raw.githubusercontent.com/conda-forge/{name}-feedstock/main/recipe/meta.yaml"
+
+var (
+	// setRegex matches Jinja2 statements setting variables, e.g. `{% set version = "1.0.0" %}`
+	setRegex = regexp.MustCompile(`\{%-?\s*set\s+(\w+)\s*=\s*["']([^"']*)["']\s*-?%\}`)
+	// exprRegex matches Jinja2 expressions, e.g. `{{ version }}` or `{{ name|lower }}`
+	exprRegex = regexp.MustCompile(`\{\{\s*(\w+)[^}]*\}\}`)
+	// stmtRegex matches any Jinja2 statements
+	stmtRegex = regexp.MustCompile(`\{%.*?%\}`)
+)
+
+// Recipe represents meta.yaml of a conda recipe
+// cf. https://docs.conda.io/projects/conda-build/en/stable/resources/define-metadata.html
+type Recipe struct {
+	Source Sources `yaml:"source"`
+	About  struct {
+		Home   string `yaml:"home"`
+		DevURL string `yaml:"dev_url"`
+	} `yaml:"about"`
+}
+
+// Sources is either a source or a list of sources
+type Sources []Source
+
+type Source struct {
+	URL    StringList `yaml:"url"` // Mirrors of the same archive
+	GitURL string     `yaml:"git_url"`
+}
+
+// StringList is either a string or a list of strings
+type StringList []string
+
+func (s *Sources) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode((*[]Source)(s))
+	}
+	var src Source
+	if err := node.Decode(&src); err != nil {
+		return err
+	}
+	*s = Sources{src}
+	return nil
+}
+
+func (s *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode((*[]string)(s))
+	}
+	*s = StringList{node.Value}
+	return nil
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the URL template of recipes, where `{name}` is replaced with the package name.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: feedstockURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("conda").With("purl", pkg.PURL.String())
+	// "conda" type doesn't have namespace, and the channel is in qualifiers
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#conda
+	recipeURL := strings.ReplaceAll(c.url, "{name}", pkg.PURL.Name)
+	errBuilder = errBuilder.With("url", recipeURL)
+
+	r, err := download.Open(ctx, recipeURL)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get recipe")
+	}
+	defer r.Close()
+
+	recipe, err := parseRecipe(r)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse recipe")
+	}
+
+	src, err := recipe.repository()
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// repository returns `about.dev_url`, the Git source, the repository hosting the source archive or `about.home` in order.
+func (r *Recipe) repository() (string, error) {
+	if r.About.DevURL != "" {
+		return r.About.DevURL, nil
+	}
+	for _, src := range r.Source {
+		if src.GitURL != "" {
+			return src.GitURL, nil
+		}
+		for _, u := range src.URL {
+			if repo, ok := xurl.ParseForge(u); ok {
+				return repo.String(), nil
+			}
+		}
+	}
+	if r.About.Home != "" {
+		return r.About.Home, nil
+	}
+	return "", oops.Errorf("source URL not found")
+}
+
+// parseRecipe renders the Jinja2 template partially and decodes it.
+// Only variables set by string literals are supported, and the others are rendered as empty.
+func parseRecipe(r io.Reader) (*Recipe, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to read recipe")
+	}
+
+	vars := make(map[string]string)
+	for _, m := range setRegex.FindAllStringSubmatch(string(b), -1) {
+		vars[m[1]] = m[2]
+	}
+	s := stmtRegex.ReplaceAllString(string(b), "")
+	s = exprRegex.ReplaceAllStringFunc(s, func(m string) string {
+		return vars[exprRegex.FindStringSubmatch(m)[1]]
+	})
+
+	var recipe Recipe
+	if err = yaml.Unmarshal([]byte(s), &recipe); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode recipe")
+	}
+	return &recipe, nil
+}
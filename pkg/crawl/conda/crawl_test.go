@@ -0,0 +1,107 @@
+package conda_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/conda"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		url     string // Defaults to the test server
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with dev_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "numpy",
+				},
+			},
+			want: "https://github.com/numpy/numpy",
+		},
+		{
+			name: "happy path with source url on GitHub",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "jq",
+				},
+			},
+			want: "https://github.com/jqlang/jq",
+		},
+		{
+			name: "happy path with git_url in local feedstock",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "pkg-config",
+				},
+			},
+			url:  "testdata/{name}-feedstock/recipe/meta.yaml",
+			want: "https://gitlab.freedesktop.org/pkg-config/pkg-config.git",
+		},
+		{
+			name: "happy path with home",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "requests",
+				},
+			},
+			want: "https://requests.readthedocs.io",
+		},
+		{
+			name: "sad path without urls",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeConda,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.url
+			if u == "" {
+				u = ts.URL + "/{name}-feedstock/recipe/meta.yaml"
+			}
+			crawler := conda.NewCrawler(conda.WithURL(u))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package conda
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeConda, func(conf config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(WithURL(conf.Conda.URL)), nil
+	})
+}
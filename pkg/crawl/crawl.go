@@ -2,81 +2,244 @@ package crawl
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"os"
 
-	"github.com/package-url/packageurl-go"
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/cargo"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/golang"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/maven"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/npm"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
-	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/pypi"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/plugin"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+
+	// Register built-in crawlers
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/apk"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/cargo"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/cocoapods"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/composer"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/conda"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/deb"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/golang"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/hackage"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/helm"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/hex"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/maven"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/npm"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/nuget"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/pub"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/pypi"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/rpm"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/rubygems"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/swift"
+	_ "github.com/aquasecurity/vexhub-crawler/pkg/crawl/vcs"
 )
 
 type Options struct {
 	VEXHubDir string
 	Packages  []config.Package
+	Crawlers  config.Crawlers
 	Strict    bool
+
+	// Git holds credentials for source repositories
+	Git config.Git
+
+	// GitCacheDir holds mirrors of source repositories between runs if set.
+	GitCacheDir string
+	// GitCacheMaxSize is the size of GitCacheDir in bytes to start evicting mirrors. Zero means no limit.
+	GitCacheMaxSize int64
+
+	// Limits bounds the resources spent on each source repository or archive. Zero fields mean no limit.
+	Limits vex.Limits
+}
+
+// Crawler is an alias for registry.Crawler.
+type Crawler = registry.Crawler
+
+// Fetcher is implemented by crawlers that can fetch VEX documents without the source repository,
+// such as OCI artifacts attached to images.
+type Fetcher interface {
+	FetchVEX(context.Context, config.Package) ([]vex.Document, error)
 }
 
-type Crawler interface {
-	DetectSrc(context.Context, config.Package) (*url.URL, error)
+// repository is a source repository and the packages in it.
+type repository struct {
+	pkgs []config.Package
+	srcs []*url.URL // Source URL of each package, pointing to the same repository and ref
 }
 
 func Packages(ctx context.Context, opts Options) error {
+	opts.Crawlers.MaxDocumentSize = opts.Limits.MaxDocumentSize
+	vexOpts := []vex.Option{
+		vex.WithAuth(newAuth(opts.Git)),
+		vex.WithLimits(opts.Limits),
+	}
+	if opts.GitCacheDir != "" {
+		vexOpts = append(vexOpts, vex.WithCache(download.NewCache(opts.GitCacheDir, opts.GitCacheMaxSize)))
+	}
+
+	// Packages sharing the source repository are crawled in a single download
+	var repos []*repository
+	index := make(map[string]*repository)
 	for _, pkg := range opts.Packages {
 		logger := slog.With(slog.String("type", pkg.PURL.Type), slog.String("purl", pkg.PURL.String()))
 		logger.Info("Crawling package...")
-		if err := crawlPackage(ctx, opts.VEXHubDir, pkg); err != nil {
-			if opts.Strict {
-				return oops.Wrapf(err, "strict")
+		src, err := detectSrc(ctx, opts, pkg)
+		if err != nil {
+			if err = handleError(opts, pkg, err); err != nil {
+				return err
 			}
-			logger.Warn(err.Error(), slog.Any("error", err))
+			continue
+		} else if src == nil {
+			continue // Crawled without the source repository
+		}
+
+		// Packages share the fetch only with the same credentials, which Canonical drops
+		key := src.Canonical() + "@" + src.Ref()
+		if src.User != nil {
+			key = src.User.String() + "|" + key
+		}
+		repo, ok := index[key]
+		if !ok {
+			repo = &repository{}
+			index[key] = repo
+			repos = append(repos, repo)
+		}
+		repo.pkgs = append(repo.pkgs, pkg)
+		repo.srcs = append(repo.srcs, src)
+	}
+
+	for _, r := range repos {
+		if err := crawlRepository(ctx, opts, r, vexOpts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crawlRepository downloads the repository once, and crawls the packages in it.
+func crawlRepository(ctx context.Context, opts Options, r *repository, vexOpts ...vex.Option) error {
+	repo, fetchErr := vex.Fetch(ctx, r.srcs, vexOpts...)
+	if fetchErr == nil {
+		defer repo.Close()
+	}
+
+	for i, pkg := range r.pkgs {
+		err := fetchErr
+		if err == nil {
+			err = repo.CrawlPackage(opts.VEXHubDir, r.srcs[i], pkg.PURL)
 		}
+		if err == nil {
+			continue
+		}
+		errBuilder := oops.Code("crawl_package").With("type", pkg.PURL.Type).With("purl", pkg.PURL.String())
+		if err = handleError(opts, pkg, errBuilder.Wrapf(err, "failed to crawl package")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleError returns the error in the strict mode, and otherwise logs it to continue with other packages.
+func handleError(opts Options, pkg config.Package, err error) error {
+	if opts.Strict {
+		return oops.Wrapf(err, "strict")
 	}
+	slog.Warn(err.Error(), slog.String("type", pkg.PURL.Type), slog.String("purl", pkg.PURL.String()), slog.Any("error", err))
 	return nil
 }
 
-func crawlPackage(ctx context.Context, vexHubDir string, pkg config.Package) error {
+// newAuth returns credentials for source repositories with environment variables expanded.
+func newAuth(conf config.Git) *download.Auth {
+	auth := &download.Auth{
+		KnownHosts: os.ExpandEnv(conf.KnownHosts),
+	}
+	for _, c := range conf.Credentials {
+		password := c.Password
+		if password == "" {
+			password = c.Token
+		}
+		auth.Credentials = append(auth.Credentials, download.Credential{
+			Host:             c.Host,
+			Username:         os.ExpandEnv(c.Username),
+			Password:         os.ExpandEnv(password),
+			SSHKey:           os.ExpandEnv(c.SSHKey),
+			SSHKeyPassphrase: os.ExpandEnv(c.SSHKeyPassphrase),
+		})
+	}
+	return auth
+}
+
+// detectSrc returns the source repository of the package.
+// It returns nil if VEX documents are found without the source repository.
+func detectSrc(ctx context.Context, opts Options, pkg config.Package) (*url.URL, error) {
 	errBuilder := oops.Code("crawl_package").With("type", pkg.PURL.Type).With("purl", pkg.PURL.String())
 
-	var src *url.URL
-	var err error
-	if pkg.URL != "" {
-		if src, err = url.Parse(pkg.URL); err != nil {
-			return errBuilder.With("url", pkg.URL).Wrapf(err, "failed to normalize URL")
+	if pkg.URL != "" && download.IsFile(ctx, pkg.URL) {
+		// VEX documents published at static URLs as they are or in archives
+		if err := vex.CrawlURL(ctx, opts.VEXHubDir, pkg.URL, pkg.PURL, vex.WithLimits(opts.Limits)); err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to crawl URL")
+		}
+		return nil, nil
+	} else if pkg.URL != "" {
+		src, err := url.Parse(pkg.URL)
+		if err != nil {
+			return nil, errBuilder.With("url", pkg.URL).Wrapf(err, "failed to normalize URL")
 		}
-	} else {
-		var crawler Crawler
-		switch pkg.PURL.Type {
-		case packageurl.TypeCargo:
-			crawler = cargo.NewCrawler()
-		case packageurl.TypeGolang:
-			crawler = golang.NewCrawler()
-		case packageurl.TypeMaven:
-			crawler = maven.NewCrawler()
-		case packageurl.TypeNPM:
-			crawler = npm.NewCrawler()
-		case packageurl.TypePyPi:
-			crawler = pypi.NewCrawler()
-		case packageurl.TypeOCI:
-			crawler = oci.NewCrawler()
-		default:
-			return oops.Errorf("unsupported package type: %s", pkg.PURL.Type)
+		return src, nil
+	} else if vcsURL, ok := pkg.PURL.Qualifiers.Map()["vcs_url"]; ok {
+		// The repository stated in the PURL takes precedence over registries
+		src, err := url.ParseVCS(vcsURL)
+		if err != nil {
+			return nil, errBuilder.With("vcs_url", vcsURL).Wrapf(err, "failed to parse vcs_url")
 		}
+		return src, nil
+	}
+
+	crawler, err := newCrawler(opts.Crawlers, pkg.PURL.Type)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to initialize the crawler")
+	}
 
-		if src, err = crawler.DetectSrc(ctx, pkg); err != nil {
-			return errBuilder.Wrapf(err, "failed to detect source repository")
+	// VEX documents published along with the package take precedence over the source repository
+	if f, ok := crawler.(Fetcher); ok {
+		docs, err := f.FetchVEX(ctx, pkg)
+		if err != nil {
+			slog.Info("Failed to fetch VEX documents, falling back to the source repository",
+				slog.String("purl", pkg.PURL.String()), slog.Any("error", err))
+		} else if len(docs) > 0 {
+			err = vex.CrawlDocuments(opts.VEXHubDir, docs, pkg.PURL, vex.WithLimits(opts.Limits))
+			if err == nil {
+				return nil, nil
+			} else if !errors.Is(err, vex.ErrNoVEXFile) {
+				return nil, errBuilder.Wrapf(err, "failed to crawl documents")
+			}
+			slog.Info("No VEX documents match the package, falling back to the source repository",
+				slog.String("purl", pkg.PURL.String()))
 		}
 	}
 
-	if err = vex.CrawlPackage(ctx, vexHubDir, src, pkg.PURL); err != nil {
-		return errBuilder.Wrapf(err, "failed to crawl package")
+	src, err := crawler.DetectSrc(ctx, pkg)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to detect source repository")
 	}
-	return nil
+	return src, nil
+}
+
+// newCrawler returns the plugin configured for the package type, or the crawler in the registry.
+func newCrawler(conf config.Crawlers, typ string) (Crawler, error) {
+	for _, p := range conf.Plugins {
+		if p.Type == typ {
+			return plugin.NewCrawler(p.Command, plugin.WithArgs(p.Args...), plugin.WithTimeout(p.Timeout)), nil
+		}
+	}
+
+	factory, ok := registry.Lookup(typ)
+	if !ok {
+		return nil, oops.Errorf("unsupported package type: %s", typ)
+	}
+	return factory(conf)
 }
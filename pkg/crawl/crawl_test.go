@@ -0,0 +1,260 @@
+package crawl_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	openvex "github.com/openvex/go-vex/pkg/vex"
+	"github.com/package-url/packageurl-go"
+	"github.com/sosedoff/gitkit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+func TestPackages(t *testing.T) {
+	tests := []struct {
+		name      string
+		pkgs      map[string]string // PURL to URL
+		strict    bool
+		wantFiles []string // Packages with VEX documents written
+		wantErr   string
+
+		// Fetches of the shared repository per fetch of the one with a single package, checked if set
+		sharedFetches int
+	}{
+		{
+			name: "happy path with packages sharing a repository",
+			pkgs: map[string]string{
+				"pkg:npm/foo@1.0.0": "{{server}}/shared.git//foo",
+				"pkg:npm/bar@1.0.0": "{{server}}/shared.git//bar",
+				"pkg:npm/baz@1.0.0": "{{server}}/single.git//baz",
+			},
+			wantFiles:     []string{"foo", "bar", "baz"},
+			sharedFetches: 1,
+		},
+		{
+			name: "happy path with packages sharing a repository with different credentials",
+			pkgs: map[string]string{
+				"pkg:npm/foo@1.0.0": "http://alice@{{host}}/shared.git//foo",
+				"pkg:npm/bar@1.0.0": "http://bob@{{host}}/shared.git//bar",
+				"pkg:npm/baz@1.0.0": "{{server}}/single.git//baz",
+			},
+			wantFiles:     []string{"foo", "bar", "baz"},
+			sharedFetches: 2,
+		},
+		{
+			name: "happy path with a missed repository",
+			pkgs: map[string]string{
+				"pkg:npm/foo@1.0.0": "{{server}}/missed.git//foo",
+				"pkg:npm/baz@1.0.0": "{{server}}/single.git//baz",
+			},
+			wantFiles: []string{"baz"},
+		},
+		{
+			// Sources of all the packages are detected before repositories are fetched,
+			// so documents at URLs are written even if a repository fails first
+			name: "sad path with a missed repository in the strict mode",
+			pkgs: map[string]string{
+				"pkg:npm/foo@1.0.0": "{{server}}/missed.git//foo",
+				"pkg:npm/qux@1.0.0": "{{server}}/qux.openvex.json",
+			},
+			strict:    true,
+			wantFiles: []string{"qux"},
+			wantErr:   "failed to crawl package",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, fetches := newServer(t)
+
+			var pkgs []config.Package
+			for _, name := range []string{"foo", "bar", "baz", "qux"} {
+				purl := "pkg:npm/" + name + "@1.0.0"
+				rawurl, ok := tt.pkgs[purl]
+				if !ok {
+					continue
+				}
+				p, err := packageurl.FromString(purl)
+				require.NoError(t, err)
+				pkgs = append(pkgs, config.Package{
+					PURL: p,
+					URL: strings.NewReplacer(
+						"{{server}}", server.URL,
+						"{{host}}", strings.TrimPrefix(server.URL, "http://"),
+					).Replace(rawurl),
+				})
+			}
+
+			vexHubDir := t.TempDir()
+			err := crawl.Packages(context.Background(), crawl.Options{
+				VEXHubDir: vexHubDir,
+				Packages:  pkgs,
+				Strict:    tt.strict,
+			})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			for _, name := range tt.wantFiles {
+				assert.DirExists(t, filepath.Join(vexHubDir, "pkg", "npm", name))
+			}
+			entries, err := os.ReadDir(filepath.Join(vexHubDir, "pkg", "npm"))
+			if len(tt.wantFiles) == 0 {
+				require.ErrorIs(t, err, os.ErrNotExist)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, entries, len(tt.wantFiles))
+
+			// The repository shared by two packages is fetched once per credentials
+			if tt.sharedFetches > 0 {
+				got := fetches()
+				assert.Equal(t, tt.sharedFetches*got["single"], got["shared"])
+			}
+		})
+	}
+}
+
+// fetcher fetches VEX documents of another package, and detects the repository with the document of "foo".
+type fetcher struct {
+	repoURL string
+}
+
+func (f fetcher) FetchVEX(context.Context, config.Package) ([]vex.Document, error) {
+	b, err := json.Marshal(testVEX("pkg:npm/other@1.0.0"))
+	if err != nil {
+		return nil, err
+	}
+	return []vex.Document{{Name: "openvex.json", Content: b, URL: "https://example.com/openvex.json"}}, nil
+}
+
+func (f fetcher) DetectSrc(context.Context, config.Package) (*url.URL, error) {
+	return url.Parse(f.repoURL)
+}
+
+func TestPackages_FetcherFallback(t *testing.T) {
+	server, _ := newServer(t)
+
+	factory, ok := registry.Lookup(packageurl.TypeNPM)
+	require.True(t, ok)
+	t.Cleanup(func() { registry.Register(packageurl.TypeNPM, factory) })
+	registry.Register(packageurl.TypeNPM, func(config.Crawlers) (registry.Crawler, error) {
+		return fetcher{repoURL: server.URL + "/shared.git//foo"}, nil
+	})
+
+	p, err := packageurl.FromString("pkg:npm/foo@1.0.0")
+	require.NoError(t, err)
+
+	// Fetched documents not matching the package fall back to the source repository
+	vexHubDir := t.TempDir()
+	err = crawl.Packages(context.Background(), crawl.Options{
+		VEXHubDir: vexHubDir,
+		Packages:  []config.Package{{PURL: p}},
+		Strict:    true,
+	})
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(vexHubDir, "pkg", "npm", "foo", "openvex.json"))
+}
+
+// newServer serves the "shared" repository with the VEX documents of "foo" and "bar",
+// the "single" repository with the document of "baz", and the document of "qux" at a static URL.
+// fetches returns the number of upload-pack requests per repository.
+func newServer(t *testing.T) (*httptest.Server, func() map[string]int) {
+	bareDir := t.TempDir()
+	newRepo(t, bareDir, "shared", "foo", "bar")
+	newRepo(t, bareDir, "single", "baz")
+	service := gitkit.New(gitkit.Config{Dir: bareDir})
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/qux.openvex.json" {
+			_ = json.NewEncoder(w).Encode(testVEX("pkg:npm/qux@1.0.0"))
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/git-upload-pack") {
+			mu.Lock()
+			counts[strings.TrimSuffix(strings.Split(r.URL.Path, "/")[1], ".git")]++
+			mu.Unlock()
+		}
+		service.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() map[string]int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts
+	}
+}
+
+// newRepo creates the bare repository with the VEX document of each package in the subdirectory of its name.
+func newRepo(t *testing.T, bareDir, repoName string, names ...string) {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	for _, name := range names {
+		b, err := json.Marshal(testVEX("pkg:npm/" + name + "@1.0.0"))
+		require.NoError(t, err)
+		vexDir := filepath.Join(wtDir, name, ".vex")
+		require.NoError(t, os.MkdirAll(vexDir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(vexDir, "openvex.json"), b, 0644))
+	}
+	_, err = wt.Add(".")
+	require.NoError(t, err)
+	_, err = wt.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+
+	bare, err := git.PlainClone(filepath.Join(bareDir, repoName+".git"), true, &git.CloneOptions{URL: wtDir})
+	require.NoError(t, err)
+	cfg, err := bare.Config()
+	require.NoError(t, err)
+	cfg.Raw.Section("uploadpack").SetOption("allowFilter", "true")
+	cfg.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+	require.NoError(t, bare.SetConfig(cfg))
+}
+
+func testVEX(productID string) openvex.VEX {
+	return openvex.VEX{
+		Metadata: openvex.Metadata{
+			Context: openvex.ContextLocator(),
+			ID:      "https://example.com/vex-1234",
+			Author:  "Example Corp.",
+			Version: 1,
+		},
+		Statements: []openvex.Statement{
+			{
+				Vulnerability: openvex.Vulnerability{ID: "CVE-2023-1234"},
+				Products: []openvex.Product{
+					{Component: openvex.Component{ID: productID}},
+				},
+				Status:        openvex.StatusNotAffected,
+				Justification: openvex.VulnerableCodeNotPresent,
+			},
+		},
+	}
+}
@@ -0,0 +1,208 @@
+package deb
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// Repository is an archive of a distribution, which can be a mirror or a local directory.
+type Repository struct {
+	Namespace  string // e.g. debian, ubuntu
+	URL        string
+	Suite      string
+	Components []string
+}
+
+var defaultRepositories = []Repository{
+	{
+		Namespace:  "debian",
+		URL:        "https://deb.debian.org/debian",
+		Suite:      "stable",
+		Components: []string{"main"},
+	},
+	{
+		Namespace:  "ubuntu",
+		URL:        "http://archive.ubuntu.com/ubuntu",
+		Suite:      "noble",
+		Components: []string{"main", "universe"},
+	},
+}
+
+// Source represents a paragraph of the Sources index
+// cf. https://wiki.debian.org/DebianRepository/Format#A.22Sources.22_Indices
+type Source struct {
+	Package  string
+	Binaries []string
+	VcsGit   string
+}
+
+type Crawler struct {
+	repos []Repository
+}
+
+type Option func(*Crawler)
+
+// WithRepositories sets archives per namespace, which take precedence over the defaults.
+func WithRepositories(repos ...Repository) Option {
+	return func(c *Crawler) {
+		c.repos = slices.Concat(repos, c.repos)
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		repos: defaultRepositories,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("deb").With("purl", pkg.PURL.String())
+	// "deb" type uses namespace for the vendor, e.g. debian or ubuntu
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#deb
+	idx := slices.IndexFunc(c.repos, func(r Repository) bool {
+		return r.Namespace == pkg.PURL.Namespace
+	})
+	if idx < 0 {
+		return nil, errBuilder.Errorf("no repository configured for namespace %q", pkg.PURL.Namespace)
+	}
+	repo := c.repos[idx]
+	errBuilder = errBuilder.With("repository", repo.URL).With("suite", repo.Suite)
+
+	src, err := c.findSource(ctx, repo, pkg.PURL.Name)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to find source package")
+	}
+	errBuilder = errBuilder.With("source", src.Package)
+	if src.VcsGit == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	// Vcs-Git may be followed by the branch and the subdirectory, e.g. `<url> -b <branch> [<path>]`
+	// cf. https://www.debian.org/doc/debian-policy/ch-controlfields.html#version-control-system-vcs-fields
+	fields := strings.Fields(src.VcsGit)
+	u, err := xurl.Parse(fields[0])
+	if err != nil {
+		return nil, errBuilder.With("src", src.VcsGit).Wrapf(err, "failed to normalize URL")
+	}
+	for i := 1; i < len(fields); i++ {
+		switch {
+		case fields[i] == "-b" && i+1 < len(fields):
+			i++
+			u.SetRef(fields[i])
+		case strings.HasPrefix(fields[i], "[") && strings.HasSuffix(fields[i], "]"):
+			u.SetSubdirs(strings.Trim(fields[i], "[]/"))
+		}
+	}
+	return u, nil
+}
+
+// findSource looks up the source package building the package in the Sources indexes of the components.
+func (c *Crawler) findSource(ctx context.Context, repo Repository, name string) (*Source, error) {
+	for _, component := range repo.Components {
+		// e.g. https://deb.debian.org/debian/dists/stable/main/source/Sources.gz
+		indexURL, err := url.JoinPath(repo.URL, "dists", repo.Suite, component, "source", "Sources.gz")
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to build index url")
+		}
+		src, err := searchIndex(ctx, indexURL, name)
+		if err != nil {
+			return nil, oops.With("component", component).Wrap(err)
+		} else if src != nil {
+			return src, nil
+		}
+	}
+	return nil, oops.With("package", name).Errorf("package not found")
+}
+
+func searchIndex(ctx context.Context, indexURL, name string) (*Source, error) {
+	errBuilder := oops.With("url", indexURL)
+	r, err := download.Open(ctx, indexURL)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get Sources index")
+	}
+	defer r.Close()
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to open gzip")
+	}
+	defer gr.Close()
+
+	src, err := parseSources(gr, func(s Source) bool {
+		return s.Package == name || slices.Contains(s.Binaries, name)
+	})
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse Sources index")
+	}
+	return src, nil
+}
+
+// parseSources returns the first paragraph matching the function.
+// Only the fields needed are parsed, and continuation lines are ignored except for Binary.
+func parseSources(r io.Reader, match func(Source) bool) (*Source, error) {
+	var src Source
+	var field string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if src.Package != "" && match(src) {
+				return &src, nil
+			}
+			src, field = Source{}, ""
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if field == "binary" {
+				src.Binaries = append(src.Binaries, splitBinaries(line)...)
+			}
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, ":")
+		field = strings.ToLower(key)
+		value = strings.TrimSpace(value)
+		switch field {
+		case "package":
+			src.Package = value
+		case "binary":
+			src.Binaries = append(src.Binaries, splitBinaries(value)...)
+		case "vcs-git":
+			src.VcsGit = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oops.Wrap(err)
+	}
+	if src.Package != "" && match(src) {
+		return &src, nil
+	}
+	return nil, nil
+}
+
+func splitBinaries(s string) []string {
+	var binaries []string
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			binaries = append(binaries, b)
+		}
+	}
+	return binaries
+}
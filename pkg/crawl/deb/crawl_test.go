@@ -0,0 +1,160 @@
+package deb_test
+
+import (
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/deb"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	mirror := newMirror(t)
+	ts := httptest.NewServer(http.FileServer(http.Dir(mirror)))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name        string
+		pkg         config.Package
+		repoURL     string // Defaults to the test server
+		want        string
+		wantRef     string
+		wantSubdirs string
+		wantErr     string
+	}{
+		{
+			name: "happy path",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "curl",
+				},
+			},
+			want: "https://salsa.debian.org/debian/curl.git",
+		},
+		{
+			name: "happy path with binary package and branch",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "libssl3t64",
+				},
+			},
+			want:    "https://salsa.debian.org/debian/openssl.git",
+			wantRef: "debian/unstable",
+		},
+		{
+			name: "happy path with binary package in continuation line",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "libcurl4-doc",
+				},
+			},
+			want: "https://salsa.debian.org/debian/curl.git",
+		},
+		{
+			name: "happy path with subdirectory in local mirror",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "libc6",
+				},
+			},
+			repoURL:     mirror,
+			want:        "https://salsa.debian.org/glibc-team/glibc.git",
+			wantRef:     "sid",
+			wantSubdirs: "debian",
+		},
+		{
+			name: "sad path without Vcs-Git",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "no-vcs",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "debian",
+					Name:      "missed",
+				},
+			},
+			wantErr: "package not found",
+		},
+		{
+			name: "sad path with unknown namespace",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeDebian,
+					Namespace: "unknown",
+					Name:      "curl",
+				},
+			},
+			wantErr: `no repository configured for namespace "unknown"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoURL := tt.repoURL
+			if repoURL == "" {
+				repoURL = ts.URL
+			}
+			crawler := deb.NewCrawler(deb.WithRepositories(deb.Repository{
+				Namespace:  "debian",
+				URL:        repoURL,
+				Suite:      "stable",
+				Components: []string{"contrib", "main"},
+			}))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, tt.wantSubdirs, got.Subdirs())
+		})
+	}
+}
+
+// newMirror builds the archive layout with testdata/Sources in main and an empty contrib.
+func newMirror(t *testing.T) string {
+	dir := t.TempDir()
+	b, err := os.ReadFile(filepath.Join("testdata", "Sources"))
+	require.NoError(t, err)
+
+	for component, content := range map[string][]byte{"main": b, "contrib": nil} {
+		path := filepath.Join(dir, "dists", "stable", component, "source", "Sources.gz")
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		gw := gzip.NewWriter(f)
+		_, err = gw.Write(content)
+		require.NoError(t, err)
+		require.NoError(t, gw.Close())
+		require.NoError(t, f.Close())
+	}
+	return dir
+}
@@ -0,0 +1,27 @@
+package deb
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeDebian, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.Deb), nil
+	})
+}
+
+func newFromConfig(conf config.Deb) *Crawler {
+	var repos []Repository
+	for _, r := range conf.Repositories {
+		repos = append(repos, Repository{
+			Namespace:  r.Namespace,
+			URL:        r.URL,
+			Suite:      r.Suite,
+			Components: r.Components,
+		})
+	}
+	return NewCrawler(WithRepositories(repos...))
+}
@@ -2,43 +2,203 @@ package golang
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"os"
 	"path"
 	"strings"
 
 	"github.com/samber/oops"
-	"golang.org/x/tools/go/vcs"
+	"golang.org/x/mod/module"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
-type Crawler struct{}
+const defaultGOPROXY = "https://proxy.golang.org,direct"
 
-func NewCrawler() *Crawler {
-	return &Crawler{}
+// repoRoot represents the repository containing the module
+type repoRoot struct {
+	repo   string // Repository URL
+	ref    string // Branch or tag, the default branch if empty
+	subdir string // Directory of the module within the repository
+
+	// fallbackSubdir is the module directory if subdir turns out not to exist.
+	// It is set when the major version suffix can be either a directory or a branch.
+	fallbackSubdir *string
+}
+
+type Crawler struct {
+	goproxy   string
+	gonoproxy string
+}
+
+type Option func(*Crawler)
+
+// WithGOPROXY sets the list of module proxies in the GOPROXY format.
+func WithGOPROXY(goproxy string) Option {
+	return func(c *Crawler) {
+		if goproxy != "" {
+			c.goproxy = goproxy
+		}
+	}
+}
+
+// WithGONOPROXY sets glob patterns of module paths that bypass the proxies, in the GONOPROXY format.
+func WithGONOPROXY(patterns string) Option {
+	return func(c *Crawler) {
+		if patterns != "" {
+			c.gonoproxy = patterns
+		}
+	}
 }
 
-func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*url.URL, error) {
+// NewCrawler returns a crawler respecting GOPROXY, GONOPROXY and GOPRIVATE environment variables by default.
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		goproxy:   defaultGOPROXY,
+		gonoproxy: os.Getenv("GONOPROXY"),
+	}
+	if v := os.Getenv("GOPROXY"); v != "" {
+		crawler.goproxy = v
+	}
+	if crawler.gonoproxy == "" {
+		crawler.gonoproxy = os.Getenv("GOPRIVATE")
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+// DetectSrc detects the source repository URL of the module.
+// It queries the origin metadata from the module proxies first,
+// and parses go-import meta tags only if the proxies cannot tell the origin.
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*url.URL, error) {
 	errBuilder := oops.Code("crawl_error").In("golang").With("purl", pkg.PURL.String())
 
 	purl := pkg.PURL
-	importPath := path.Join(purl.Namespace, purl.Name, purl.Subpath)
+	modulePath := path.Join(purl.Namespace, purl.Name)
 
-	errBuilder = errBuilder.With("url", importPath)
-	repoRoot, err := vcs.RepoRootForImportPath(importPath, false)
+	errBuilder = errBuilder.With("module", modulePath)
+	root, err := c.resolve(ctx, modulePath)
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get repo root")
 	}
 
-	u, err := url.Parse(repoRoot.Repo)
+	u, err := url.Parse(root.repo)
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to parse URL")
 	}
 
-	subPath := strings.TrimPrefix(importPath, repoRoot.Root)
-	if subPath != "" {
+	if subPath := path.Join(root.subdir, purl.Subpath); subPath != "" {
 		// cf. https://github.com/hashicorp/go-getter?tab=readme-ov-file#subdirectories
-		u.SetSubdirs(strings.TrimPrefix(subPath, "/"))
+		u.SetSubdirs(subPath)
+	}
+	if root.fallbackSubdir != nil {
+		u.SetFallbackSubdirs(path.Join(*root.fallbackSubdir, purl.Subpath))
+	}
+	if root.ref != "" {
+		u.SetRef(root.ref)
 	}
 	return u, nil
 }
+
+// resolve walks through GOPROXY in the same way as the go command.
+// cf. https://go.dev/ref/mod#goproxy-protocol
+func (c *Crawler) resolve(ctx context.Context, modulePath string) (*repoRoot, error) {
+	if module.MatchPrefixPatterns(c.gonoproxy, modulePath) {
+		return c.resolveDirect(ctx, modulePath)
+	}
+
+	var errs []error
+	for _, p := range parseProxyList(c.goproxy) {
+		switch p.url {
+		case proxyOff:
+			errs = append(errs, oops.Errorf("module lookup disabled by GOPROXY=off"))
+			return nil, errors.Join(errs...)
+		case proxyDirect:
+			return c.resolveDirect(ctx, modulePath)
+		}
+
+		info, err := c.fetchInfo(ctx, p.url, modulePath)
+		if err == nil {
+			if info.Origin != nil && info.Origin.URL != "" {
+				slog.Info("Found module origin", slog.String("module", modulePath),
+					slog.String("proxy", p.url), slog.String("url", info.Origin.URL))
+				return &repoRoot{
+					repo:   info.Origin.URL,
+					subdir: info.Origin.Subdir,
+				}, nil
+			}
+			// Some proxies don't serve the origin metadata
+			slog.Info("No origin in module info, falling back to go-import meta tags",
+				slog.String("module", modulePath), slog.String("proxy", p.url))
+			return c.resolveDirect(ctx, modulePath)
+		}
+
+		errs = append(errs, err)
+		if !p.fallThrough && !errors.Is(err, errNotFound) {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return nil, oops.Errorf("no module proxy configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// resolveDirect resolves the repository without module proxies.
+func (c *Crawler) resolveDirect(ctx context.Context, importPath string) (*repoRoot, error) {
+	host, _, _ := strings.Cut(importPath, "/")
+	if !strings.Contains(host, ".") {
+		return nil, oops.With("import_path", importPath).Errorf("import path does not begin with hostname")
+	}
+
+	var root *repoRoot
+	switch host {
+	case "github.com", "bitbucket.org":
+		// Well-known code hosting sites don't need HTTP requests
+		parts := strings.Split(importPath, "/")
+		if len(parts) < 3 {
+			return nil, oops.With("import_path", importPath).Errorf("invalid import path")
+		}
+		root = &repoRoot{
+			repo:   "https://" + path.Join(parts[:3]...),
+			subdir: path.Join(parts[3:]...),
+		}
+	default:
+		im, src, err := c.fetchMetaImport(ctx, importPath)
+		if err != nil {
+			return nil, err
+		}
+		root = &repoRoot{
+			repo:   im.RepoRoot,
+			ref:    src.ref(),
+			subdir: strings.TrimPrefix(strings.TrimPrefix(importPath, im.Prefix), "/"),
+		}
+	}
+
+	root.fallbackSubdir = majorBranchDir(importPath, root.subdir)
+	return root, nil
+}
+
+// majorBranchDir returns the module directory in the "major branch" layout
+// if the module path has a major version suffix, e.g. github.com/foo/bar/v3.
+// Such a module lives either in the "v3" subdirectory (major subdirectory)
+// or in the parent directory on a branch (major branch), and it cannot be told without the repository.
+// +incompatible versions have no suffix and live in the directory as is.
+// cf. https://go.dev/ref/mod#vcs-version
+func majorBranchDir(modulePath, subdir string) *string {
+	_, pathMajor, ok := module.SplitPathVersion(modulePath)
+	if !ok || !strings.HasPrefix(pathMajor, "/") { // gopkg.in uses ".v3" which is not a directory
+		return nil
+	}
+
+	major := strings.TrimPrefix(pathMajor, "/")
+	if subdir != major && !strings.HasSuffix(subdir, "/"+major) {
+		return nil
+	}
+	dir := strings.TrimSuffix(strings.TrimSuffix(subdir, major), "/")
+	return &dir
+}
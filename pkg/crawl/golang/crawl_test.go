@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/package-url/packageurl-go"
@@ -17,13 +18,21 @@ import (
 )
 
 func TestCrawler_DetectSrc(t *testing.T) {
+	t.Setenv("GONOPROXY", "")
+	t.Setenv("GOPRIVATE", "")
+
 	tests := []struct {
 		name        string
 		pkg         config.Package
+		goproxy     string // "{{server}}" is replaced with the mock server URL
+		gonoproxy   string
 		mockServer  func(w http.ResponseWriter, r *http.Request)
 		want        string
 		wantSubDirs string
-		wantErr     bool
+		wantRef     string
+		// wantFallbackSubDirs is set for modules with a major version suffix
+		wantFallbackSubDirs []string
+		wantErr             bool
 	}{
 		{
 			name: "GitHub repository",
@@ -33,6 +42,7 @@ func TestCrawler_DetectSrc(t *testing.T) {
 					Name: "github.com/example/repo",
 				},
 			},
+			goproxy: "direct",
 			mockServer: func(w http.ResponseWriter, r *http.Request) {
 				assert.Fail(t, "unexpected HTTP call")
 			},
@@ -48,6 +58,7 @@ func TestCrawler_DetectSrc(t *testing.T) {
 					Subpath: "foo/bar",
 				},
 			},
+			goproxy: "direct",
 			mockServer: func(w http.ResponseWriter, r *http.Request) {
 				assert.Fail(t, "unexpected HTTP call")
 			},
@@ -63,6 +74,7 @@ func TestCrawler_DetectSrc(t *testing.T) {
 					Name: "invalid-domain/repo",
 				},
 			},
+			goproxy: "direct",
 			mockServer: func(w http.ResponseWriter, r *http.Request) {
 				assert.Fail(t, "unexpected HTTP call")
 			},
@@ -75,6 +87,7 @@ func TestCrawler_DetectSrc(t *testing.T) {
 					Type: "golang",
 				},
 			},
+			goproxy: "direct",
 			mockServer: func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				_, err := w.Write([]byte(fmt.Sprintf(`<html><head>
@@ -85,6 +98,195 @@ func TestCrawler_DetectSrc(t *testing.T) {
 			want:    "https://github.com/org/repo.git",
 			wantErr: false,
 		},
+		{
+			name: "GitHub repository with major version suffix",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      "golang",
+					Namespace: "github.com/example",
+					Name:      "repo/v3",
+				},
+			},
+			goproxy: "direct",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				assert.Fail(t, "unexpected HTTP call")
+			},
+			want:                "https://github.com/example/repo",
+			wantSubDirs:         "v3",
+			wantFallbackSubDirs: []string{""},
+		},
+		{
+			name: "nested module with major version suffix",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:    "golang",
+					Name:    "github.com/example/repo/sub/v2",
+					Subpath: "pkg",
+				},
+			},
+			goproxy: "direct",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				assert.Fail(t, "unexpected HTTP call")
+			},
+			want:                "https://github.com/example/repo",
+			wantSubDirs:         "sub/v2/pkg",
+			wantFallbackSubDirs: []string{"sub/pkg"},
+		},
+		{
+			name: "success - custom domain with go-source meta tag",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+				},
+			},
+			goproxy: "direct",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(fmt.Sprintf(`<html><head>
+					<meta name="go-import" content="%[1]s git https://github.com/org/repo">
+					<meta name="go-source" content="%[1]s https://github.com/org/repo https://github.com/org/repo/tree/release-1.0{/dir} https://github.com/org/repo/blob/release-1.0{/dir}/{file}#L{line}">
+				</head></html>`, r.Host)))
+				assert.NoError(t, err)
+			},
+			want:    "https://github.com/org/repo",
+			wantRef: "release-1.0",
+		},
+		{
+			name: "success - origin from proxy",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      "golang",
+					Namespace: "go.example.com",
+					Name:      "Foo",
+				},
+			},
+			goproxy: "{{server}}",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/go.example.com/!foo/@latest", r.URL.Path)
+				_, err := w.Write([]byte(`{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://github.com/org/mono","Subdir":"foo","Ref":"refs/tags/foo/v1.2.3"}}`))
+				assert.NoError(t, err)
+			},
+			want:        "https://github.com/org/mono",
+			wantSubDirs: "foo",
+		},
+		{
+			name: "success - origin from version info",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      "golang",
+					Namespace: "go.example.com",
+					Name:      "foo",
+					Subpath:   "pkg/bar",
+				},
+			},
+			goproxy: "{{server}}",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				var resp string
+				switch r.URL.Path {
+				case "/go.example.com/foo/@latest":
+					resp = `{"Version":"v1.2.3"}`
+				case "/go.example.com/foo/@v/v1.2.3.info":
+					resp = `{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://github.com/org/foo"}}`
+				default:
+					assert.Fail(t, "unexpected HTTP call", r.URL.Path)
+				}
+				_, err := w.Write([]byte(resp))
+				assert.NoError(t, err)
+			},
+			want:        "https://github.com/org/foo",
+			wantSubDirs: "pkg/bar",
+		},
+		{
+			name: "success - no origin in proxy",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "github.com/example/repo",
+				},
+			},
+			goproxy: "{{server}}",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(`{"Version":"v1.2.3"}`))
+				assert.NoError(t, err)
+			},
+			want: "https://github.com/example/repo",
+		},
+		{
+			name: "success - fall through to the next proxy on 404",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "go.example.com/foo",
+				},
+			},
+			goproxy: "{{server}}/missing,{{server}}",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/missing/") {
+					w.WriteHeader(http.StatusGone)
+					return
+				}
+				_, err := w.Write([]byte(`{"Version":"v1.2.3","Origin":{"VCS":"git","URL":"https://github.com/org/foo"}}`))
+				assert.NoError(t, err)
+			},
+			want: "https://github.com/org/foo",
+		},
+		{
+			name: "success - fall through to direct on any error",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "github.com/example/repo",
+				},
+			},
+			goproxy: "{{server}}|direct",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			want: "https://github.com/example/repo",
+		},
+		{
+			name: "success - private module bypasses proxy",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "github.com/example/repo",
+				},
+			},
+			goproxy:   "{{server}}",
+			gonoproxy: "github.com/example",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				assert.Fail(t, "unexpected HTTP call")
+			},
+			want: "https://github.com/example/repo",
+		},
+		{
+			name: "failure - no fall through on server error",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "github.com/example/repo",
+				},
+			},
+			goproxy: "{{server}},direct",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr: true,
+		},
+		{
+			name: "failure - GOPROXY=off",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: "golang",
+					Name: "github.com/example/repo",
+				},
+			},
+			goproxy: "off",
+			mockServer: func(w http.ResponseWriter, r *http.Request) {
+				assert.Fail(t, "unexpected HTTP call")
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,7 +301,10 @@ func TestCrawler_DetectSrc(t *testing.T) {
 				tt.pkg.PURL.Name = u.Host
 			}
 
-			c := golang.NewCrawler()
+			c := golang.NewCrawler(
+				golang.WithGOPROXY(strings.ReplaceAll(tt.goproxy, "{{server}}", ts.URL)),
+				golang.WithGONOPROXY(tt.gonoproxy),
+			)
 			got, err := c.DetectSrc(context.Background(), tt.pkg)
 			if tt.wantErr {
 				require.Error(t, err)
@@ -108,6 +313,8 @@ func TestCrawler_DetectSrc(t *testing.T) {
 			require.NoError(t, err)
 			assert.Equal(t, tt.want, got.String())
 			assert.Equal(t, tt.wantSubDirs, got.Subdirs())
+			assert.Equal(t, tt.wantRef, got.Ref())
+			assert.Equal(t, tt.wantFallbackSubDirs, got.FallbackSubdirs())
 		})
 	}
 }
@@ -0,0 +1,190 @@
+package golang
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/samber/oops"
+)
+
+// metaImport represents a go-import meta tag
+// cf. https://go.dev/ref/mod#vcs-find
+type metaImport struct {
+	Prefix, VCS, RepoRoot string
+}
+
+// metaSource represents a go-source meta tag
+// cf. https://github.com/golang/gddo/wiki/Source-Code-Links
+type metaSource struct {
+	Prefix, Home, Directory, File string
+}
+
+type metaTags struct {
+	imports []metaImport
+	sources []metaSource
+}
+
+// fetchMetaImport fetches `?go-get=1` of the import path and returns the go-import meta tag matching the path,
+// along with the go-source meta tag for the same prefix if any.
+// HTTPS is tried first and HTTP next, as golang.org/x/tools/go/vcs did.
+func (c *Crawler) fetchMetaImport(ctx context.Context, importPath string) (*metaImport, *metaSource, error) {
+	var errs []error
+	for _, scheme := range []string{"https", "http"} {
+		tags, err := c.fetchMetaTags(ctx, scheme+"://"+importPath+"?go-get=1")
+		if err == nil {
+			var im *metaImport
+			if im, err = matchMetaImport(tags.imports, importPath); err == nil {
+				return im, matchMetaSource(tags.sources, im.Prefix), nil
+			}
+		}
+		errs = append(errs, err)
+	}
+	return nil, nil, errors.Join(errs...)
+}
+
+func (c *Crawler) fetchMetaTags(ctx context.Context, rawurl string) (*metaTags, error) {
+	errBuilder := oops.With("url", rawurl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get go-import meta tag")
+	}
+	defer resp.Body.Close()
+	// Non-200 status codes are accepted as some servers serve meta tags in their 404 pages.
+
+	tags, err := parseMetaTags(resp.Body)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse go-import meta tag")
+	}
+	return tags, nil
+}
+
+// parseMetaTags returns go-import and go-source meta tags in <head>.
+func parseMetaTags(r io.Reader) (*metaTags, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "utf-8") || strings.EqualFold(charset, "ascii") {
+			return input, nil
+		}
+		return nil, oops.Errorf("can't decode XML document using charset %q", charset)
+	}
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var tags metaTags
+	for {
+		t, err := d.RawToken()
+		if err != nil {
+			if errors.Is(err, io.EOF) || len(tags.imports) > 0 {
+				return &tags, nil
+			}
+			return nil, err
+		}
+		if e, ok := t.(xml.StartElement); ok && strings.EqualFold(e.Name.Local, "body") {
+			return &tags, nil
+		}
+		if e, ok := t.(xml.EndElement); ok && strings.EqualFold(e.Name.Local, "head") {
+			return &tags, nil
+		}
+		e, ok := t.(xml.StartElement)
+		if !ok || !strings.EqualFold(e.Name.Local, "meta") {
+			continue
+		}
+		f := strings.Fields(attrValue(e.Attr, "content"))
+		switch attrValue(e.Attr, "name") {
+		case "go-import":
+			if len(f) == 3 {
+				tags.imports = append(tags.imports, metaImport{
+					Prefix:   f[0],
+					VCS:      f[1],
+					RepoRoot: f[2],
+				})
+			}
+		case "go-source":
+			if len(f) == 4 {
+				tags.sources = append(tags.sources, metaSource{
+					Prefix:    f[0],
+					Home:      f[1],
+					Directory: f[2],
+					File:      f[3],
+				})
+			}
+		}
+	}
+}
+
+// matchMetaImport returns the meta tag whose prefix matches the import path.
+// "mod" entries are ignored since we need the VCS repository.
+func matchMetaImport(imports []metaImport, importPath string) (*metaImport, error) {
+	var match *metaImport
+	for i, im := range imports {
+		if im.VCS == "mod" {
+			continue
+		} else if importPath != im.Prefix && !strings.HasPrefix(importPath, im.Prefix+"/") {
+			continue
+		}
+		if match != nil {
+			return nil, oops.With("import_path", importPath).Errorf("multiple meta tags match import path")
+		}
+		match = &imports[i]
+	}
+	if match == nil {
+		return nil, oops.With("import_path", importPath).Errorf("go-import meta tag not found")
+	}
+	return match, nil
+}
+
+// matchMetaSource returns the go-source meta tag for the prefix of go-import.
+func matchMetaSource(sources []metaSource, prefix string) *metaSource {
+	for i, src := range sources {
+		if src.Prefix == prefix {
+			return &sources[i]
+		}
+	}
+	return nil
+}
+
+// ref extracts the branch or tag from the directory template.
+// e.g. https://github.com/kubernetes/client-go/tree/master{/dir} => master
+//
+//	https://gitlab.com/org/repo/-/tree/main{/dir} => main
+//	https://gitea.com/org/repo/src/branch/main{/dir} => main
+func (s *metaSource) ref() string {
+	if s == nil || s.Directory == "_" {
+		return ""
+	}
+	tmpl, _, found := strings.Cut(s.Directory, "{")
+	if !found {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	for i := len(parts) - 2; i >= 0; i-- {
+		if parts[i] != "tree" && parts[i] != "src" {
+			continue
+		}
+		ref := parts[i+1]
+		if (ref == "branch" || ref == "tag" || ref == "commit") && i+2 < len(parts) {
+			ref = parts[i+2]
+		}
+		return ref
+	}
+	return ""
+}
+
+func attrValue(attrs []xml.Attr, name string) string {
+	for _, a := range attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package golang
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/samber/oops"
+	"golang.org/x/mod/module"
+)
+
+var errNotFound = errors.New("module not found")
+
+// Info represents the response of `@latest` and `@v/<version>.info`
+// cf. https://go.dev/ref/mod#goproxy-protocol
+type Info struct {
+	Version string  `json:"Version"`
+	Origin  *Origin `json:"Origin"`
+}
+
+// Origin represents the VCS origin of the module version
+type Origin struct {
+	VCS    string `json:"VCS"`
+	URL    string `json:"URL"`
+	Subdir string `json:"Subdir"`
+}
+
+// proxy is an entry of GOPROXY
+type proxy struct {
+	url string
+	// fallThrough is true if the next proxy should be tried on any error (separated by `|`),
+	// otherwise only on 404 or 410 (separated by `,`).
+	fallThrough bool
+}
+
+const (
+	proxyDirect = "direct"
+	proxyOff    = "off"
+)
+
+// parseProxyList parses GOPROXY
+// cf. https://go.dev/ref/mod#goproxy-protocol
+func parseProxyList(s string) []proxy {
+	var proxies []proxy
+	for s != "" {
+		var p proxy
+		i := strings.IndexAny(s, ",|")
+		if i < 0 {
+			p.url, s = s, ""
+		} else {
+			p.url, p.fallThrough, s = s[:i], s[i] == '|', s[i+1:]
+		}
+		if p.url = strings.TrimSpace(p.url); p.url != "" {
+			proxies = append(proxies, p)
+		}
+	}
+	return proxies
+}
+
+// fetchInfo fetches the latest version info of the module from the proxy.
+// If `@latest` doesn't contain the origin, `.info` of the version is tried.
+func (c *Crawler) fetchInfo(ctx context.Context, proxyURL, modulePath string) (*Info, error) {
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to escape module path")
+	}
+
+	latestURL, err := url.JoinPath(proxyURL, escaped, "@latest")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build url")
+	}
+	info, err := c.getInfo(ctx, latestURL)
+	if err != nil {
+		return nil, err
+	} else if info.Origin != nil || info.Version == "" {
+		return info, nil
+	}
+
+	escapedVersion, err := module.EscapeVersion(info.Version)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to escape version")
+	}
+	infoURL, err := url.JoinPath(proxyURL, escaped, "@v", escapedVersion+".info")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build url")
+	}
+	return c.getInfo(ctx, infoURL)
+}
+
+func (c *Crawler) getInfo(ctx context.Context, rawurl string) (*Info, error) {
+	errBuilder := oops.With("url", rawurl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get module info")
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, errBuilder.Wrapf(errNotFound, "failed to get module info: %s", resp.Status)
+	default:
+		return nil, errBuilder.Errorf("failed to get module info: %s", resp.Status)
+	}
+
+	var info Info
+	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode response")
+	}
+	return &info, nil
+}
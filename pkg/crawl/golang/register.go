@@ -0,0 +1,17 @@
+package golang
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeGolang, func(conf config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(
+			WithGOPROXY(conf.Golang.GOPROXY),
+			WithGONOPROXY(conf.Golang.GONOPROXY),
+		), nil
+	})
+}
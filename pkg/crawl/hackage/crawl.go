@@ -0,0 +1,194 @@
+package hackage
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const hackageURL = "https://hackage.haskell.org"
+
+// SourceRepository represents the `source-repository` stanza of a cabal file
+// cf. https://cabal.readthedocs.io/en/stable/cabal-package-description-file.html#source-repositories
+type SourceRepository struct {
+	Kind     string // "head" or "this"
+	Type     string
+	Location string
+	Tag      string
+	Branch   string
+	Subdir   string
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the Hackage server.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		c.url = url
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: hackageURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("hackage").With("purl", pkg.PURL.String())
+	// "hackage" type doesn't have namespace
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#hackage
+	// Default url format is `https://hackage.haskell.org/package/<name>/<name>.cabal`, which is the latest revision of the latest version
+	cabalURL, err := url.JoinPath(c.url, "package", pkg.PURL.Name, pkg.PURL.Name+".cabal")
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build cabal url")
+	}
+
+	errBuilder = errBuilder.With("url", cabalURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cabalURL, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get cabal file")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get cabal file: %s", resp.Status)
+	}
+
+	repos, err := parseSourceRepositories(resp.Body)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse cabal file")
+	}
+
+	repo := selectRepository(repos)
+	if repo == nil {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	// GitHub no longer supports the unauthenticated git protocol, while many cabal files still use it
+	src := repo.Location
+	if after, ok := strings.CutPrefix(src, "git://github.com/"); ok {
+		src = "https://github.com/" + after
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	if repo.Subdir != "" {
+		u.SetSubdirs(repo.Subdir)
+	}
+	// "this" points to the released version, while "head" follows the development branch
+	if repo.Kind == "this" && repo.Tag != "" {
+		u.SetRef(repo.Tag)
+	} else if repo.Branch != "" {
+		u.SetRef(repo.Branch)
+	}
+	return u, nil
+}
+
+// selectRepository returns the Git repository, preferring "head" to "this".
+func selectRepository(repos []SourceRepository) *SourceRepository {
+	var found *SourceRepository
+	for i, repo := range repos {
+		// Other version control systems such as darcs are not supported
+		if repo.Location == "" || (repo.Type != "" && repo.Type != "git") {
+			continue
+		}
+		if repo.Kind == "head" {
+			return &repos[i]
+		} else if found == nil {
+			found = &repos[i]
+		}
+	}
+	return found
+}
+
+// parseSourceRepositories extracts `source-repository` stanzas from the cabal file.
+// Sections start at column 0, and field values may continue on more indented lines.
+func parseSourceRepositories(r io.Reader) ([]SourceRepository, error) {
+	var repos []SourceRepository
+	var repo *SourceRepository
+	var field *string
+	fieldIndent := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent == 0 {
+			// A new top-level field or section
+			field = nil
+			repo = nil
+			name, kind, _ := strings.Cut(trimmed, " ")
+			if strings.EqualFold(name, "source-repository") {
+				repos = append(repos, SourceRepository{Kind: strings.ToLower(strings.TrimSpace(kind))})
+				repo = &repos[len(repos)-1]
+			}
+			continue
+		} else if repo == nil {
+			continue
+		}
+
+		// Continuation of the previous field
+		if field != nil && indent > fieldIndent {
+			*field = strings.TrimSpace(*field + " " + trimmed)
+			continue
+		}
+
+		name, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			field = nil
+			continue
+		}
+		fieldIndent = indent
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "type":
+			repo.Type = strings.ToLower(value)
+			field = &repo.Type
+		case "location":
+			repo.Location = value
+			field = &repo.Location
+		case "tag":
+			repo.Tag = value
+			field = &repo.Tag
+		case "branch":
+			repo.Branch = value
+			field = &repo.Branch
+		case "subdir":
+			repo.Subdir = value
+			field = &repo.Subdir
+		default:
+			field = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oops.Wrapf(err, "failed to read cabal file")
+	}
+	return repos, nil
+}
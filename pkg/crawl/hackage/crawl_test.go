@@ -0,0 +1,97 @@
+package hackage_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/hackage"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name        string
+		pkg         config.Package
+		want        string
+		wantRef     string
+		wantSubdirs string
+		wantErr     string
+	}{
+		{
+			name: "happy path with head repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHackage,
+					Name: "aeson",
+				},
+			},
+			want: "https://github.com/haskell/aeson.git",
+		},
+		{
+			name: "happy path with this repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHackage,
+					Name: "lens",
+				},
+			},
+			want:        "https://github.com/ekmett/lens.git",
+			wantRef:     "v5.3.2",
+			wantSubdirs: "lens",
+		},
+		{
+			name: "sad path with non-git repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHackage,
+					Name: "darcs",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path when cabal file doesn't contain source repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHackage,
+					Name: "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHackage,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get cabal file: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+			t.Cleanup(ts.Close)
+
+			crawler := hackage.NewCrawler(hackage.WithURL(ts.URL))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, tt.wantSubdirs, got.Subdirs())
+		})
+	}
+}
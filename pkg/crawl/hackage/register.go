@@ -0,0 +1,14 @@
+package hackage
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeHackage, func(config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(), nil
+	})
+}
@@ -0,0 +1,245 @@
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/samber/oops"
+	"golang.org/x/mod/semver"
+	"gopkg.in/yaml.v3"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// Metadata represents Chart.yaml, which is also stored in index.yaml and the config of OCI-hosted charts
+// cf. https://helm.sh/docs/topics/charts/#the-chartyaml-file
+type Metadata struct {
+	Name    string   `yaml:"name" json:"name"`
+	Version string   `yaml:"version" json:"version"`
+	Home    string   `yaml:"home" json:"home"`
+	Sources []string `yaml:"sources" json:"sources"`
+}
+
+// Index represents index.yaml of a chart repository
+// cf. https://helm.sh/docs/topics/chart_repository/#the-index-file
+type Index struct {
+	Entries map[string][]Metadata `yaml:"entries"`
+}
+
+// Repository is a chart repository, which is either an HTTP(S) repository or an OCI registry with the "oci://" scheme.
+type Repository struct {
+	Name     string // Referred to by the namespace of PURLs, as in `helm repo add`
+	URL      string
+	Username string
+	Password string
+}
+
+type Crawler struct {
+	repos []Repository
+}
+
+type Option func(*Crawler)
+
+// WithRepositories sets chart repositories.
+func WithRepositories(repos ...Repository) Option {
+	return func(c *Crawler) {
+		c.repos = append(c.repos, repos...)
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("helm").With("purl", pkg.PURL.String())
+	repoURL, err := c.repositoryURL(pkg)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	errBuilder = errBuilder.With("repository", repoURL)
+
+	var chart *Metadata
+	if strings.HasPrefix(repoURL, "oci://") {
+		chart, err = c.fetchFromRegistry(ctx, repoURL, pkg.PURL.Name)
+	} else {
+		chart, err = c.fetchFromIndex(ctx, repoURL, pkg.PURL.Name)
+	}
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get chart metadata")
+	}
+	errBuilder = errBuilder.With("version", chart.Version)
+
+	// `home` is often the project website, so `sources` is preferred
+	src := chart.Home
+	if len(chart.Sources) > 0 {
+		src = chart.Sources[0]
+	}
+	if src == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// repositoryURL returns the chart repository from the `repository_url` qualifier,
+// or the configured repository named by the namespace.
+func (c *Crawler) repositoryURL(pkg config.Package) (string, error) {
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		return repositoryURL, nil
+	}
+	for _, repo := range c.repos {
+		if repo.Name != "" && repo.Name == pkg.PURL.Namespace {
+			return repo.URL, nil
+		}
+	}
+	return "", oops.With("namespace", pkg.PURL.Namespace).
+		Errorf("repository_url qualifier or configured repository is required")
+}
+
+// fetchFromIndex returns the latest version of the chart in index.yaml.
+func (c *Crawler) fetchFromIndex(ctx context.Context, repoURL, chart string) (*Metadata, error) {
+	indexURL, err := url.JoinPath(repoURL, "index.yaml")
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to build index url")
+	}
+
+	errBuilder := oops.With("url", indexURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, indexURL, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+	if repo := c.credentials(req.URL.Host); repo != nil {
+		req.SetBasicAuth(repo.Username, repo.Password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get index.yaml")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get index.yaml: %s", resp.Status)
+	}
+
+	var index Index
+	if err = yaml.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode index.yaml")
+	}
+
+	versions := index.Entries[chart]
+	if len(versions) == 0 {
+		return nil, errBuilder.With("chart", chart).Errorf("chart not found")
+	}
+	latest := &versions[0]
+	for i, v := range versions {
+		if isNewer(v.Version, latest.Version) {
+			latest = &versions[i]
+		}
+	}
+	return latest, nil
+}
+
+// fetchFromRegistry returns Chart.yaml of the latest version stored in the config of the OCI artifact.
+// cf. https://helm.sh/docs/topics/registries/#helm-chart-manifest
+func (c *Crawler) fetchFromRegistry(ctx context.Context, repoURL, chart string) (*Metadata, error) {
+	repoName := strings.TrimSuffix(strings.TrimPrefix(repoURL, "oci://"), "/") + "/" + chart
+	errBuilder := oops.With("repository", repoName)
+	repo, err := name.NewRepository(repoName)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse repository")
+	}
+
+	var keychain authn.Keychain = authn.DefaultKeychain
+	if r := c.credentials(repo.RegistryStr()); r != nil {
+		keychain = authn.NewMultiKeychain(oci.StaticKeychain{
+			repo.RegistryStr(): {Username: r.Username, Password: r.Password},
+		}, authn.DefaultKeychain)
+	}
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(keychain)}
+
+	tags, err := remote.List(repo, opts...)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to list tags")
+	}
+	var latest string
+	for _, tag := range tags {
+		// OCI tags can't contain "+", so Helm replaces it with "_"
+		if v := strings.ReplaceAll(tag, "_", "+"); semver.IsValid("v"+v) && (latest == "" || isNewer(v, latest)) {
+			latest = v
+		}
+	}
+	if latest == "" {
+		return nil, errBuilder.Errorf("no chart version found")
+	}
+
+	ref := repo.Tag(strings.ReplaceAll(latest, "+", "_"))
+	img, err := remote.Image(ref, opts...)
+	if err != nil {
+		return nil, errBuilder.With("ref", ref.Name()).Wrapf(err, "failed to get chart")
+	}
+	b, err := img.RawConfigFile()
+	if err != nil {
+		return nil, errBuilder.With("ref", ref.Name()).Wrapf(err, "failed to get chart config")
+	}
+
+	var m Metadata
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, errBuilder.With("ref", ref.Name()).Wrapf(err, "failed to decode chart config")
+	}
+	return &m, nil
+}
+
+// credentials returns the configured repository hosted on the host.
+// Credentials are sent only to the hosts of configured repositories.
+func (c *Crawler) credentials(host string) *Repository {
+	for i, repo := range c.repos {
+		if repo.Username == "" && repo.Password == "" {
+			continue
+		}
+		u, err := url.Parse(repo.URL)
+		if err == nil && u.Host != "" && normalizeHost(u.Host) == normalizeHost(host) {
+			return &c.repos[i]
+		}
+	}
+	return nil
+}
+
+// normalizeHost returns the host as go-containerregistry sees registries, e.g. "docker.io" => "index.docker.io",
+// so that configured hosts match registries of OCI repositories.
+func normalizeHost(host string) string {
+	if reg, err := name.NewRegistry(host); err == nil {
+		return reg.RegistryStr()
+	}
+	return host
+}
+
+// isNewer reports whether the version a is newer than b, preferring stable versions to prereleases.
+// Invalid versions are never newer.
+func isNewer(a, b string) bool {
+	va, vb := "v"+strings.TrimPrefix(a, "v"), "v"+strings.TrimPrefix(b, "v")
+	if !semver.IsValid(va) {
+		return false
+	} else if !semver.IsValid(vb) {
+		return true
+	}
+	if pa, pb := semver.Prerelease(va) != "", semver.Prerelease(vb) != ""; pa != pb {
+		return pb
+	}
+	return semver.Compare(va, vb) > 0
+}
@@ -0,0 +1,235 @@
+package helm_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/helm"
+)
+
+const chartConfigMediaType types.MediaType = "application/vnd.cncf.helm.config.v1+json"
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	// Ignore the Docker config of the host
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	fs := http.FileServer(http.Dir("testdata"))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); strings.HasPrefix(r.URL.Path, "/private/") &&
+			(!ok || user != "user" || pass != "pass") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fs.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+
+	reg := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(reg.Close)
+	regHost := strings.TrimPrefix(reg.URL, "http://")
+	pushChart(t, regHost+"/charts/podinfo:6.6.0", helm.Metadata{
+		Name:    "podinfo",
+		Version: "6.6.0",
+		Sources: []string{"https://github.com/stefanprodan/podinfo-old"},
+	})
+	pushChart(t, regHost+"/charts/podinfo:6.7.0_build.1", helm.Metadata{
+		Name:    "podinfo",
+		Version: "6.7.0+build.1",
+		Home:    "https://github.com/stefanprodan/podinfo",
+	})
+
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		repos   []helm.Repository
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with sources",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHelm,
+					Name: "nginx",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: ts.URL,
+						},
+					},
+				},
+			},
+			want: "https://github.com/bitnami/charts",
+		},
+		{
+			name: "happy path with home and configured repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeHelm,
+					Namespace: "jetstack",
+					Name:      "cert-manager",
+				},
+			},
+			repos: []helm.Repository{
+				{
+					Name: "jetstack",
+					URL:  ts.URL,
+				},
+			},
+			want: "https://github.com/cert-manager/cert-manager",
+		},
+		{
+			name: "happy path with private repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeHelm,
+					Namespace: "private",
+					Name:      "internal",
+				},
+			},
+			repos: []helm.Repository{
+				{
+					Name:     "private",
+					URL:      ts.URL + "/private",
+					Username: "user",
+					Password: "pass",
+				},
+			},
+			want: "https://github.com/example/internal",
+		},
+		{
+			name: "happy path with OCI registry",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHelm,
+					Name: "podinfo",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "oci://" + regHost + "/charts",
+						},
+					},
+				},
+			},
+			want: "https://github.com/stefanprodan/podinfo",
+		},
+		{
+			name: "sad path with private repository without credentials",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHelm,
+					Name: "internal",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: ts.URL + "/private",
+						},
+					},
+				},
+			},
+			wantErr: "failed to get index.yaml: 401 Unauthorized",
+		},
+		{
+			name: "sad path when chart doesn't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHelm,
+					Name: "no-url",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: ts.URL,
+						},
+					},
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed chart",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHelm,
+					Name: "missed",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: ts.URL,
+						},
+					},
+				},
+			},
+			wantErr: "chart not found",
+		},
+		{
+			name: "sad path without repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeHelm,
+					Namespace: "unknown",
+					Name:      "nginx",
+				},
+			},
+			wantErr: "repository_url qualifier or configured repository is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := helm.NewCrawler(helm.WithRepositories(tt.repos...))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+// chartManifest is the manifest of a chart pushed by `helm push`, whose config is Chart.yaml in JSON.
+type chartManifest []byte
+
+func (m chartManifest) RawManifest() ([]byte, error) { return m, nil }
+
+func (m chartManifest) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func pushChart(t *testing.T, refStr string, chart helm.Metadata) {
+	ref, err := name.ParseReference(refStr)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(chart)
+	require.NoError(t, err)
+	cfg := static.NewLayer(b, chartConfigMediaType)
+	require.NoError(t, remote.WriteLayer(ref.Context(), cfg))
+
+	desc, err := partial.Descriptor(cfg)
+	require.NoError(t, err)
+	manifest, err := json.Marshal(v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		Config:        *desc,
+		Layers:        []v1.Descriptor{},
+	})
+	require.NoError(t, err)
+	require.NoError(t, remote.Put(ref, chartManifest(manifest)))
+}
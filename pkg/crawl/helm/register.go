@@ -0,0 +1,29 @@
+package helm
+
+import (
+	"os"
+
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeHelm, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.Helm), nil
+	})
+}
+
+func newFromConfig(conf config.Helm) *Crawler {
+	var repos []Repository
+	for _, r := range conf.Repositories {
+		repos = append(repos, Repository{
+			Name:     r.Name,
+			URL:      r.URL,
+			Username: os.ExpandEnv(r.Username),
+			Password: os.ExpandEnv(r.Password),
+		})
+	}
+	return NewCrawler(WithRepositories(repos...))
+}
@@ -0,0 +1,131 @@
+package hex
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const hexAPI = "https://hex.pm/api"
+
+// linkKeys are the names of `meta.links` pointing to the source repository, in order of preference.
+// Names are free-form and compared case-insensitively.
+var linkKeys = []string{"source", "repository", "github", "gitlab", "bitbucket", "codeberg"}
+
+// Response represents the package metadata
+// cf. https://github.com/hexpm/specifications/blob/main/apiary.apib
+type Response struct {
+	Meta struct {
+		Links map[string]string `json:"links"`
+	} `json:"meta"`
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the Hex API URL.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		c.url = url
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: hexAPI,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("hex").With("purl", pkg.PURL.String())
+	// "hex" type uses namespace for the organization of private packages, and names are lowercase
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#hex
+	apiURL := c.url
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		apiURL = repositoryURL
+	}
+
+	// Default url format is `https://hex.pm/api/packages/<name>`,
+	// or `https://hex.pm/api/repos/<organization>/packages/<name>` for private packages
+	elems := []string{"packages", strings.ToLower(pkg.PURL.Name)}
+	if pkg.PURL.Namespace != "" {
+		elems = append([]string{"repos", strings.ToLower(pkg.PURL.Namespace)}, elems...)
+	}
+	hexURL, err := url.JoinPath(apiURL, elems...)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build package url")
+	}
+
+	errBuilder = errBuilder.With("url", hexURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hexURL, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get package info")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get package info: %s", resp.Status)
+	}
+
+	var r Response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode response")
+	}
+
+	src := sourceLink(r.Meta.Links)
+	if src == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// sourceLink returns the link to the source repository.
+// Links with unknown names are used only if they point to well-known forges.
+func sourceLink(links map[string]string) string {
+	normalized := make(map[string]string, len(links))
+	for k, v := range links {
+		normalized[strings.ToLower(k)] = v
+	}
+	for _, key := range linkKeys {
+		if v := normalized[key]; v != "" {
+			return v
+		}
+	}
+
+	// Sort names to be deterministic
+	keys := make([]string, 0, len(normalized))
+	for k := range normalized {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, ok := xurl.ParseForge(normalized[k]); ok {
+			return normalized[k]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package hex_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/hex"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHex,
+					Name: "phoenix",
+				},
+			},
+			want: "https://github.com/phoenixframework/phoenix",
+		},
+		{
+			name: "happy path with unknown link name",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHex,
+					Name: "plug_crypto",
+				},
+			},
+			want: "https://github.com/elixir-plug/plug_crypto",
+		},
+		{
+			name: "happy path with organization",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeHex,
+					Namespace: "acme",
+					Name:      "private",
+				},
+			},
+			want: "https://gitlab.example.com/acme/private",
+		},
+		{
+			name: "sad path when links don't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHex,
+					Name: "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeHex,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get package info: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+			t.Cleanup(ts.Close)
+
+			crawler := hex.NewCrawler(hex.WithURL(ts.URL + "/api"))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -3,10 +3,12 @@ package maven
 import (
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"path"
+	"strconv"
 	"strings"
 
 	"github.com/samber/oops"
@@ -17,41 +19,90 @@ import (
 
 const mavenRepo = "https://repo.maven.apache.org/maven2"
 
+// maxRelocations limits the number of relocations followed to avoid loops
+const maxRelocations = 5
+
 // Metadata represents maven-metadata.xml
 type Metadata struct {
 	Versioning Versioning `xml:"versioning"`
 }
 
 type Versioning struct {
-	Latest string `xml:"latest"`
+	Latest   string   `xml:"latest"`
+	Release  string   `xml:"release"`
+	Versions []string `xml:"versions>version"`
 }
 
 // POM represents pom.xml
 type POM struct {
-	XMLName xml.Name `xml:"project"`
-	SCM     Scm      `xml:"scm"`
-	URL     string   `xml:"url"`
+	XMLName                xml.Name               `xml:"project"`
+	SCM                    Scm                    `xml:"scm"`
+	URL                    string                 `xml:"url"`
+	DistributionManagement DistributionManagement `xml:"distributionManagement"`
 }
 
 type Scm struct {
 	URL string `xml:"url"`
 }
 
+type DistributionManagement struct {
+	Relocation *Relocation `xml:"relocation"`
+}
+
+// Relocation represents the new coordinates of a moved artifact
+// cf. https://maven.apache.org/guides/mini/guide-relocation.html
+type Relocation struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+}
+
+// Repository is a Maven repository to query
+type Repository struct {
+	ID  string // Used to look up credentials in settings.xml
+	URL string
+}
+
+// coordinates identifies an artifact in a Maven repository
+type coordinates struct {
+	groupID    string
+	artifactID string
+	version    string // Resolved from maven-metadata.xml if empty
+}
+
 type Crawler struct {
-	url string
+	repos    []Repository
+	settings *Settings
 }
 
 type Option func(*Crawler)
 
+// WithURL makes the crawler query only the given repository.
 func WithURL(url string) Option {
 	return func(c *Crawler) {
-		c.url = url
+		c.repos = []Repository{{URL: url}}
+	}
+}
+
+// WithRepositories sets the repositories to be tried in order.
+func WithRepositories(repos ...Repository) Option {
+	return func(c *Crawler) {
+		if len(repos) > 0 {
+			c.repos = repos
+		}
+	}
+}
+
+// WithSettings sets settings.xml to read repository credentials from.
+func WithSettings(s *Settings) Option {
+	return func(c *Crawler) {
+		c.settings = s
 	}
 }
 
 func NewCrawler(opts ...Option) *Crawler {
 	crawler := &Crawler{
-		url: mavenRepo,
+		repos: []Repository{{ID: "central", URL: mavenRepo}},
 	}
 	for _, opt := range opts {
 		opt(crawler)
@@ -62,60 +113,99 @@ func NewCrawler(opts ...Option) *Crawler {
 // DetectSrc detects the source repository URL of the package.
 // It fetches the latest version and POM file to extract the repository URL
 // as we didn't find a way to get the repository URL directly from the metadata.
-func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*url.URL, error) {
+// Repositories are tried in order, and relocations are followed to the new coordinates.
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*url.URL, error) {
 	errBuilder := oops.Code("crawl_error").In("maven").With("purl", pkg.PURL.String())
 
 	purl := pkg.PURL
 
-	repoURL := c.url
+	repos := c.repos
 	if v, ok := purl.Qualifiers.Map()["repository_url"]; ok {
-		repoURL = v
+		repos = []Repository{{URL: v}}
 	}
 
-	baseURL, err := url.Parse(repoURL)
-	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to parse repository URL")
+	coords := coordinates{
+		groupID:    purl.Namespace,
+		artifactID: purl.Name,
 	}
 
-	// GroupID (purl.Name) can contain `.`.
-	// e.g. pkg:maven/ai.catboost/catboost-spark-aggregate_2.11@1.2.5 => https://repo.maven.apache.org/maven2/ai/catboost/catboost-spark-aggregate_2.11/1.2.5/
-	namespace := strings.ReplaceAll(purl.Namespace, ".", "/")
-	baseURL.Path = path.Join(baseURL.Path, namespace, purl.Name)
-
-	latest, err := c.fetchLatestVersion(baseURL)
-	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to fetch the latest version")
+	for range maxRelocations + 1 {
+		pom, err := c.resolvePOM(ctx, repos, coords)
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to fetch POM")
+		}
+
+		if r := pom.DistributionManagement.Relocation; r != nil {
+			coords = coords.relocate(*r)
+			slog.Info("Artifact relocated", slog.String("purl", purl.String()),
+				slog.String("groupId", coords.groupID), slog.String("artifactId", coords.artifactID))
+			continue
+		}
+
+		srcURL, err := c.extractScrURL(pom)
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to extract repository URL")
+		}
+
+		u, err := url.Parse(srcURL)
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to normalize URL")
+		}
+
+		return u, nil
 	}
-	slog.Info(
-		"Latest version found",
-		slog.String("purl", purl.String()), slog.String("version", latest),
-	)
 
-	pom, err := c.fetchPOM(baseURL, purl.Name, latest)
-	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to fetch POM")
+	return nil, errBuilder.Errorf("too many relocations")
+}
+
+// resolvePOM returns the POM of the artifact from the first repository that has it.
+func (c *Crawler) resolvePOM(ctx context.Context, repos []Repository, coords coordinates) (*POM, error) {
+	var errs []error
+	for _, repo := range repos {
+		pom, err := c.fetchArtifactPOM(ctx, repo, coords)
+		if err == nil {
+			return pom, nil
+		}
+		errs = append(errs, err)
 	}
+	return nil, errors.Join(errs...)
+}
 
-	srcURL, err := c.extractScrURL(pom)
+func (c *Crawler) fetchArtifactPOM(ctx context.Context, repo Repository, coords coordinates) (*POM, error) {
+	errBuilder := oops.With("repository", repo.URL)
+
+	baseURL, err := url.Parse(repo.URL)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to extract repository URL")
+		return nil, errBuilder.Wrapf(err, "failed to parse repository URL")
 	}
 
-	u, err := url.Parse(srcURL)
-	if err != nil {
-		return nil, errBuilder.Wrapf(err, "failed to normalize URL")
+	// GroupID (purl.Name) can contain `.`.
+	// e.g. pkg:maven/ai.catboost/catboost-spark-aggregate_2.11@1.2.5 => https://repo.maven.apache.org/maven2/ai/catboost/catboost-spark-aggregate_2.11/1.2.5/
+	namespace := strings.ReplaceAll(coords.groupID, ".", "/")
+	baseURL.Path = path.Join(baseURL.Path, namespace, coords.artifactID)
+
+	version := coords.version
+	if version == "" {
+		if version, err = c.fetchLatestVersion(ctx, repo, baseURL); err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to fetch the latest version")
+		}
+		slog.Info(
+			"Latest version found",
+			slog.String("groupId", coords.groupID), slog.String("artifactId", coords.artifactID),
+			slog.String("version", version), slog.String("repository", repo.URL),
+		)
 	}
 
-	return u, nil
+	return c.fetchPOM(ctx, repo, baseURL, coords.artifactID, version)
 }
 
-func (c *Crawler) fetchLatestVersion(baseURL *url.URL) (string, error) {
+func (c *Crawler) fetchLatestVersion(ctx context.Context, repo Repository, baseURL *url.URL) (string, error) {
 	metaURL := *baseURL.URL
 	metaURL.Path = path.Join(metaURL.Path, "maven-metadata.xml")
 
 	errBuilder := oops.Code("fetch_latest_version_error").With("metadata url", metaURL.String())
 
-	resp, err := http.Get(metaURL.String())
+	resp, err := c.get(ctx, repo, metaURL.String())
 	if err != nil {
 		return "", errBuilder.Wrapf(err, "failed to get artifact metadata")
 	}
@@ -129,19 +219,20 @@ func (c *Crawler) fetchLatestVersion(baseURL *url.URL) (string, error) {
 		return "", errBuilder.Wrapf(err, "failed to decode response")
 	}
 
-	if metadata.Versioning.Latest == "" {
+	latest := metadata.Versioning.latestVersion()
+	if latest == "" {
 		return "", errBuilder.Errorf("no latest version found")
 	}
 
-	return metadata.Versioning.Latest, nil
+	return latest, nil
 }
 
-func (c *Crawler) fetchPOM(baseURL *url.URL, name, latest string) (*POM, error) {
+func (c *Crawler) fetchPOM(ctx context.Context, repo Repository, baseURL *url.URL, name, latest string) (*POM, error) {
 	pomURL := *baseURL.URL
 	pomURL.Path = path.Join(pomURL.Path, latest, fmt.Sprintf("%s-%s.pom", name, latest))
 
 	errBuilder := oops.Code("fetch_pom_error").With("pom url", pomURL.String())
-	resp, err := http.Get(pomURL.String())
+	resp, err := c.get(ctx, repo, pomURL.String())
 	if err != nil {
 		return nil, errBuilder.Wrapf(err, "failed to get package info")
 	}
@@ -157,6 +248,18 @@ func (c *Crawler) fetchPOM(baseURL *url.URL, name, latest string) (*POM, error)
 	return &pom, nil
 }
 
+// get sends a GET request with the credentials configured for the repository, if any.
+func (c *Crawler) get(ctx context.Context, repo Repository, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create request")
+	}
+	if server, ok := c.settings.server(repo.ID); ok {
+		req.SetBasicAuth(server.Username, server.Password)
+	}
+	return http.DefaultClient.Do(req)
+}
+
 func (c *Crawler) extractScrURL(pom *POM) (string, error) {
 	if pom.SCM.URL != "" {
 		return pom.SCM.URL, nil
@@ -168,3 +271,85 @@ func (c *Crawler) extractScrURL(pom *POM) (string, error) {
 
 	return "", oops.Errorf("no repository URL found")
 }
+
+// relocate returns the coordinates after the relocation.
+// Missing elements are inherited from the current coordinates,
+// but the version is resolved again unless it is explicitly specified.
+func (c coordinates) relocate(r Relocation) coordinates {
+	relocated := coordinates{
+		groupID:    c.groupID,
+		artifactID: c.artifactID,
+		version:    r.Version,
+	}
+	if r.GroupID != "" {
+		relocated.groupID = r.GroupID
+	}
+	if r.ArtifactID != "" {
+		relocated.artifactID = r.ArtifactID
+	}
+	return relocated
+}
+
+// latestVersion returns `latest`, falling back to `release` and then the highest version in `versions`.
+func (v Versioning) latestVersion() string {
+	switch {
+	case v.Latest != "":
+		return v.Latest
+	case v.Release != "":
+		return v.Release
+	}
+
+	var highest string
+	for _, ver := range v.Versions {
+		if highest == "" || compareVersions(ver, highest) > 0 {
+			highest = ver
+		}
+	}
+	return highest
+}
+
+// compareVersions compares Maven versions in a simplified way.
+// Numeric parts are compared as numbers and qualifiers (e.g. "rc1") as strings.
+// A version with a qualifier is considered older than the same version without it.
+// e.g. 2.17.0-rc1 < 2.17.0 < 2.17.1 < 2.17.10
+func compareVersions(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '-' })
+	}
+	as, bs := split(a), split(b)
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		switch {
+		case i >= len(as):
+			return -compareVersionRest(bs[i])
+		case i >= len(bs):
+			return compareVersionRest(as[i])
+		}
+
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return an - bn
+			}
+		case aErr == nil: // Numbers are newer than qualifiers
+			return 1
+		case bErr == nil:
+			return -1
+		default:
+			if cmp := strings.Compare(strings.ToLower(as[i]), strings.ToLower(bs[i])); cmp != 0 {
+				return cmp
+			}
+		}
+	}
+	return 0
+}
+
+// compareVersionRest compares a version having the extra part with one running out of parts.
+// e.g. 1.0.1 > 1.0, 1.0-rc1 < 1.0
+func compareVersionRest(extra string) int {
+	if _, err := strconv.Atoi(extra); err == nil {
+		return 1
+	}
+	return -1
+}
@@ -107,7 +107,20 @@ func TestCrawler_DetectSrc(t *testing.T) {
 			wantErr: "XML syntax error on line",
 		},
 		{
-			name:    "sad path when maven-metadata.xml doesn't contain latest version",
+			name:    "happy path with release version",
+			repoDir: filepath.Join("testdata", "release-version"),
+			pkg: config.Package{
+				// pkg:maven/com.fasterxml.jackson.core/jackson-core
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeMaven,
+					Namespace: "com.fasterxml.jackson.core",
+					Name:      "jackson-core",
+				},
+			},
+			want: "https://github.com/FasterXML/jackson-core",
+		},
+		{
+			name:    "happy path with the highest version when maven-metadata.xml doesn't contain latest version",
 			repoDir: filepath.Join("testdata", "no-latest-version"),
 			pkg: config.Package{
 				// pkg:maven/com.fasterxml.jackson.core/jackson-core
@@ -117,6 +130,32 @@ func TestCrawler_DetectSrc(t *testing.T) {
 					Name:      "jackson-core",
 				},
 			},
+			want: "https://github.com/FasterXML/jackson-core",
+		},
+		{
+			name:    "happy path with relocation",
+			repoDir: filepath.Join("testdata", "relocation"),
+			pkg: config.Package{
+				// pkg:maven/mysql/mysql-connector-java
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeMaven,
+					Namespace: "mysql",
+					Name:      "mysql-connector-java",
+				},
+			},
+			want: "https://github.com/mysql/mysql-connector-j",
+		},
+		{
+			name:    "sad path when maven-metadata.xml doesn't contain any version",
+			repoDir: filepath.Join("testdata", "no-version"),
+			pkg: config.Package{
+				// pkg:maven/com.fasterxml.jackson.core/jackson-core
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeMaven,
+					Namespace: "com.fasterxml.jackson.core",
+					Name:      "jackson-core",
+				},
+			},
 			wantErr: "no latest version found",
 		},
 		{
@@ -191,3 +230,73 @@ func TestCrawler_DetectSrc(t *testing.T) {
 		)
 	}
 }
+
+func TestCrawler_DetectSrc_Repositories(t *testing.T) {
+	t.Setenv("VEXHUB_TEST_MAVEN_PASSWORD", "secret")
+
+	// Maven Central-like repository without the artifact
+	central := httptest.NewServer(http.FileServer(http.Dir(filepath.Join("testdata", "no-exist"))))
+	t.Cleanup(central.Close)
+
+	// Internal repository requiring credentials
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "deployer" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.FileServer(http.Dir(filepath.Join("testdata", "scm-url"))).ServeHTTP(w, r)
+	}))
+	t.Cleanup(internal.Close)
+
+	pkg := config.Package{
+		// pkg:maven/com.fasterxml.jackson.core/jackson-core
+		PURL: packageurl.PackageURL{
+			Type:      packageurl.TypeMaven,
+			Namespace: "com.fasterxml.jackson.core",
+			Name:      "jackson-core",
+		},
+	}
+	repos := []maven.Repository{
+		{ID: "central", URL: central.URL},
+		{ID: "internal", URL: internal.URL},
+	}
+
+	tests := []struct {
+		name     string
+		settings string
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "happy path",
+			settings: filepath.Join("testdata", "settings", "settings.xml"),
+			want:     "https://github.com/FasterXML/jackson-core",
+		},
+		{
+			name:    "sad path without credentials",
+			wantErr: "failed to get artifact metadata: 401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []maven.Option{maven.WithRepositories(repos...)}
+			if tt.settings != "" {
+				settings, err := maven.LoadSettings(tt.settings)
+				require.NoError(t, err)
+				opts = append(opts, maven.WithSettings(settings))
+			}
+
+			crawler := maven.NewCrawler(opts...)
+			got, err := crawler.DetectSrc(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, "failed to get artifact metadata: 404 Not Found")
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package maven
+
+import (
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeMaven, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.Maven)
+	})
+}
+
+func newFromConfig(conf config.Maven) (*Crawler, error) {
+	var repos []Repository
+	for _, r := range conf.Repositories {
+		repos = append(repos, Repository{
+			ID:  r.ID,
+			URL: r.URL,
+		})
+	}
+	opts := []Option{WithRepositories(repos...)}
+
+	if conf.Settings != "" {
+		settings, err := LoadSettings(conf.Settings)
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to load settings.xml")
+		}
+		opts = append(opts, WithSettings(settings))
+	}
+	return NewCrawler(opts...), nil
+}
@@ -0,0 +1,65 @@
+package maven
+
+import (
+	"encoding/xml"
+	"os"
+	"regexp"
+
+	"github.com/samber/oops"
+)
+
+// Settings represents the subset of Maven's settings.xml used by the crawler
+// cf. https://maven.apache.org/settings.html#servers
+type Settings struct {
+	XMLName xml.Name `xml:"settings"`
+	Servers []Server `xml:"servers>server"`
+}
+
+type Server struct {
+	ID       string `xml:"id"`
+	Username string `xml:"username"`
+	Password string `xml:"password"`
+}
+
+// LoadSettings reads settings.xml from the given path.
+// `${env.NAME}` references are expanded from the environment as Maven does.
+func LoadSettings(filePath string) (*Settings, error) {
+	errBuilder := oops.Code("load_settings_error").In("maven").With("filePath", filePath)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "file open error")
+	}
+	defer f.Close()
+
+	var s Settings
+	if err = xml.NewDecoder(f).Decode(&s); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode the file")
+	}
+
+	for i, server := range s.Servers {
+		s.Servers[i].Username = expandEnv(server.Username)
+		s.Servers[i].Password = expandEnv(server.Password)
+	}
+	return &s, nil
+}
+
+// server returns the server entry with the given ID
+func (s *Settings) server(id string) (Server, bool) {
+	if s == nil || id == "" {
+		return Server{}, false
+	}
+	for _, server := range s.Servers {
+		if server.ID == id {
+			return server, true
+		}
+	}
+	return Server{}, false
+}
+
+var envRef = regexp.MustCompile(`\$\{env\.([^}]+)\}`)
+
+func expandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(envRef.FindStringSubmatch(ref)[1])
+	})
+}
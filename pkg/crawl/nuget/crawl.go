@@ -0,0 +1,252 @@
+package nuget
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const (
+	nugetServiceIndex = "https://api.nuget.org/v3/index.json"
+
+	registrationsResource = "RegistrationsBaseUrl"
+	packageBaseResource   = "PackageBaseAddress/3.0.0"
+)
+
+// ServiceIndex represents the entry point of the NuGet V3 API
+// cf. https://learn.microsoft.com/en-us/nuget/api/service-index
+type ServiceIndex struct {
+	Resources []Resource `json:"resources"`
+}
+
+type Resource struct {
+	ID   string `json:"@id"`
+	Type string `json:"@type"`
+}
+
+// RegistrationIndex represents the registration index of a package
+// cf. https://learn.microsoft.com/en-us/nuget/api/registration-base-url-resource
+type RegistrationIndex struct {
+	Items []RegistrationPage `json:"items"`
+}
+
+// RegistrationPage holds versions of the package. Items are not inlined if the package has many versions.
+type RegistrationPage struct {
+	ID    string             `json:"@id"`
+	Items []RegistrationLeaf `json:"items"`
+}
+
+type RegistrationLeaf struct {
+	CatalogEntry struct {
+		Version string `json:"version"`
+		Listed  *bool  `json:"listed"` // Listed if absent
+	} `json:"catalogEntry"`
+}
+
+// Nuspec represents the package manifest
+// cf. https://learn.microsoft.com/en-us/nuget/reference/nuspec
+type Nuspec struct {
+	Metadata struct {
+		Repository struct {
+			Type string `xml:"type,attr"`
+			URL  string `xml:"url,attr"`
+		} `xml:"repository"`
+		ProjectURL string `xml:"projectUrl"`
+	} `xml:"metadata"`
+}
+
+type Crawler struct {
+	url      string
+	apiKey   string
+	username string
+	password string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the service index of the feed.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+// WithAPIKey sets the API key sent in the X-NuGet-ApiKey header to the feed.
+func WithAPIKey(key string) Option {
+	return func(c *Crawler) {
+		c.apiKey = key
+	}
+}
+
+// WithBasicAuth sets credentials for the feed, e.g. a personal access token for Azure Artifacts or GitHub Packages.
+func WithBasicAuth(username, password string) Option {
+	return func(c *Crawler) {
+		c.username = username
+		c.password = password
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: nugetServiceIndex,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("nuget").With("purl", pkg.PURL.String())
+	// "nuget" type doesn't have namespace, and IDs are case-insensitive
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#nuget
+	id := strings.ToLower(pkg.PURL.Name)
+	indexURL := c.url
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		indexURL = repositoryURL
+	}
+	errBuilder = errBuilder.With("service_index", indexURL)
+
+	var index ServiceIndex
+	if err := c.get(ctx, indexURL, &index); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get service index")
+	}
+	registrations := index.resource(registrationsResource + "/3.6.0") // Includes SemVer 2.0.0 packages
+	if registrations == "" {
+		registrations = index.resource(registrationsResource)
+	}
+	packageBase := index.resource(packageBaseResource)
+	if registrations == "" || packageBase == "" {
+		return nil, errBuilder.Errorf("required resources not found in service index")
+	}
+
+	version, err := c.latestVersion(ctx, registrations, id)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get the latest version")
+	}
+	errBuilder = errBuilder.With("version", version)
+
+	// Nuspec URL format is `{PackageBaseAddress}/{id}/{version}/{id}.nuspec`
+	// cf. https://learn.microsoft.com/en-us/nuget/api/package-base-address-resource#download-package-manifest-nuspec
+	nuspecURL, err := url.JoinPath(packageBase, id, version, id+".nuspec")
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build nuspec url")
+	}
+	var nuspec Nuspec
+	if err = c.get(ctx, nuspecURL, &nuspec); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get nuspec")
+	}
+
+	src := nuspec.Metadata.Repository.URL
+	if src == "" {
+		src = nuspec.Metadata.ProjectURL
+	}
+	if src == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// latestVersion returns the latest listed version, preferring stable versions to prereleases.
+// The version is normalized as in the package base address, i.e. lowercased without build metadata.
+func (c *Crawler) latestVersion(ctx context.Context, registrations, id string) (string, error) {
+	indexURL, err := url.JoinPath(registrations, id, "index.json")
+	if err != nil {
+		return "", oops.Wrapf(err, "failed to build registration url")
+	}
+	var index RegistrationIndex
+	if err = c.get(ctx, indexURL, &index); err != nil {
+		return "", oops.Wrapf(err, "failed to get registration index")
+	}
+
+	// Pages and leaves are sorted in ascending order of versions
+	var prerelease string
+	for i := len(index.Items) - 1; i >= 0; i-- {
+		page := index.Items[i]
+		if page.Items == nil {
+			if err = c.get(ctx, page.ID, &page); err != nil {
+				return "", oops.Wrapf(err, "failed to get registration page")
+			}
+		}
+		for j := len(page.Items) - 1; j >= 0; j-- {
+			entry := page.Items[j].CatalogEntry
+			if entry.Listed != nil && !*entry.Listed {
+				continue
+			}
+			version, _, _ := strings.Cut(strings.ToLower(entry.Version), "+")
+			if !strings.Contains(version, "-") {
+				return version, nil
+			} else if prerelease == "" {
+				prerelease = version
+			}
+		}
+	}
+	if prerelease != "" {
+		slog.Info("No stable version found, using prerelease", slog.String("id", id), slog.String("version", prerelease))
+		return prerelease, nil
+	}
+	return "", oops.Errorf("no listed version found")
+}
+
+// get fetches the JSON or XML resource.
+// Credentials are sent only to the host of the configured feed.
+func (c *Crawler) get(ctx context.Context, rawurl string, v any) error {
+	errBuilder := oops.With("url", rawurl)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawurl, nil)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to create request")
+	}
+	if feed, err := url.Parse(c.url); err == nil && feed.Host == req.URL.Host {
+		if c.apiKey != "" {
+			req.Header.Set("X-NuGet-ApiKey", c.apiKey)
+		}
+		if c.username != "" || c.password != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to get")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errBuilder.Errorf("failed to get: %s", resp.Status)
+	}
+
+	if strings.HasSuffix(req.URL.Path, ".nuspec") {
+		err = xml.NewDecoder(resp.Body).Decode(v)
+	} else {
+		err = json.NewDecoder(resp.Body).Decode(v)
+	}
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to decode response")
+	}
+	return nil
+}
+
+// resource returns the URL of the first resource of the type, or the type with any version if it has no version.
+func (index ServiceIndex) resource(typ string) string {
+	for _, r := range index.Resources {
+		if r.Type == typ || (!strings.Contains(typ, "/") && strings.HasPrefix(r.Type, typ+"/")) {
+			return r.ID
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package nuget_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/nuget"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		feed    string // Path to the service index
+		opts    []nuget.Option
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with repository url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Newtonsoft.Json",
+				},
+			},
+			want: "https://github.com/JamesNK/Newtonsoft.Json",
+		},
+		{
+			name: "happy path with registration pages not inlined",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Paged",
+				},
+			},
+			want: "https://github.com/example/paged",
+		},
+		{
+			name: "happy path with only prereleases",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Prerelease",
+				},
+			},
+			want: "https://github.com/example/prerelease.git",
+		},
+		{
+			name: "happy path with private feed",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Internal",
+				},
+			},
+			feed: "/private/v3/index.json",
+			opts: []nuget.Option{nuget.WithAPIKey("secret")},
+			want: "https://github.com/example/internal",
+		},
+		{
+			name: "happy path with repository_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Internal",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "{{server}}/private/v3/index.json",
+						},
+					},
+				},
+			},
+			opts: []nuget.Option{nuget.WithAPIKey("secret")},
+			want: "https://github.com/example/internal",
+		},
+		{
+			name: "sad path with private feed without API key",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "Internal",
+				},
+			},
+			feed:    "/private/v3/index.json",
+			wantErr: "failed to get service index",
+		},
+		{
+			name: "sad path when nuspec doesn't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "No-URL",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeNuget,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get registration index",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ts *httptest.Server
+			ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/private/") && r.Header.Get("X-NuGet-ApiKey") != "secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				f, err := os.ReadFile(filepath.Join("testdata", r.URL.Path))
+				if err != nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+
+				// Resources need to point to the test server
+				f = bytes.ReplaceAll(f, []byte("{{server}}"), []byte(ts.URL))
+				_, err = w.Write(f)
+				require.NoError(t, err)
+			}))
+			t.Cleanup(ts.Close)
+
+			for i, q := range tt.pkg.PURL.Qualifiers {
+				tt.pkg.PURL.Qualifiers[i].Value = strings.ReplaceAll(q.Value, "{{server}}", ts.URL)
+			}
+
+			feed := tt.feed
+			if feed == "" {
+				feed = "/v3/index.json"
+			}
+			opts := append([]nuget.Option{nuget.WithURL(ts.URL + feed)}, tt.opts...)
+
+			crawler := nuget.NewCrawler(opts...)
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package nuget
+
+import (
+	"os"
+
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeNuget, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.NuGet), nil
+	})
+}
+
+func newFromConfig(conf config.NuGet) *Crawler {
+	opts := []Option{WithURL(conf.URL)}
+	if conf.APIKey != "" {
+		opts = append(opts, WithAPIKey(os.ExpandEnv(conf.APIKey)))
+	}
+	if conf.Username != "" || conf.Password != "" {
+		opts = append(opts, WithBasicAuth(os.ExpandEnv(conf.Username), os.ExpandEnv(conf.Password)))
+	}
+	return NewCrawler(opts...)
+}
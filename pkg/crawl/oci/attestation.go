@@ -0,0 +1,228 @@
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+const (
+	dsseArtifactType     = "application/vnd.dsse.envelope.v1+json"
+	inTotoPayloadType    = "application/vnd.in-toto+json"
+	openVEXPredicateType = "https://openvex.dev/ns"
+)
+
+// envelope represents a DSSE envelope
+// cf. https://github.com/secure-systems-lab/dsse/blob/master/envelope.md
+type envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []signature `json:"signatures"`
+}
+
+type signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// statement represents an in-toto statement
+// cf. https://github.com/in-toto/attestation/blob/main/spec/v1/statement.md
+type statement struct {
+	Subject       []subject       `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// WithPublicKeys makes the crawler verify attestations with the keys.
+// Attestations signed by none of the keys are skipped. They are not verified if no key is given.
+func WithPublicKeys(keys ...crypto.PublicKey) Option {
+	return func(c *Crawler) {
+		c.publicKeys = append(c.publicKeys, keys...)
+	}
+}
+
+// LoadPublicKey loads a PEM-encoded public key, such as cosign.pub.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	errBuilder := oops.With("path", path)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to read the public key")
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errBuilder.Errorf("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse the public key")
+	}
+	return key, nil
+}
+
+// fetchAttestations returns OpenVEX documents in in-toto attestations attached to the subject,
+// either as the cosign `.att` tag or as referrers.
+func (c *Crawler) fetchAttestations(ctx context.Context, subject name.Digest) ([]vex.Document, error) {
+	layers, err := c.cosignLayers(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	referrers, err := c.referrerLayers(ctx, subject, dsseArtifactType)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []vex.Document
+	for _, l := range append(layers, referrers...) {
+		logger := slog.With(slog.String("artifact", l.artifact.Name()), slog.String("layer", l.digest.String()))
+		predicate, err := c.extractVEX(l.content, subject)
+		if err != nil {
+			logger.Warn("Skipping attestation", slog.Any("error", err))
+			continue
+		} else if predicate == nil {
+			continue
+		}
+		logger.Info("Found OpenVEX attestation")
+		docs = append(docs, l.document(predicate))
+	}
+	return docs, nil
+}
+
+// cosignLayers returns the layers of the `.att` tag of the subject if it exists.
+// cf. https://github.com/sigstore/cosign/blob/main/specs/ATTESTATION_SPEC.md
+func (c *Crawler) cosignLayers(ctx context.Context, subject name.Digest) ([]artifactLayer, error) {
+	tag := subject.Context().Tag(strings.Replace(subject.DigestStr(), ":", "-", 1) + ".att")
+	desc, err := remote.Get(tag, c.remoteOptions(ctx)...)
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, oops.With("tag", tag.Name()).Wrapf(err, "getting attestations")
+	}
+
+	img, err := desc.Image()
+	if err != nil {
+		return nil, oops.With("tag", tag.Name()).Wrapf(err, "reading attestations")
+	}
+	artifact := subject.Context().Digest(desc.Digest.String())
+	slog.Info("Found attestations", slog.String("subject", subject.Name()), slog.String("artifact", artifact.Name()))
+	return readLayers(artifact, img, c.maxDocumentSize)
+}
+
+// extractVEX decodes the DSSE envelope and returns the predicate if it is OpenVEX, otherwise nil.
+// The statement must be about the image, so that attestations copied from other images are rejected.
+func (c *Crawler) extractVEX(content []byte, image name.Digest) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode DSSE envelope")
+	} else if env.PayloadType != inTotoPayloadType {
+		return nil, nil
+	}
+
+	payload, err := decodeBase64(env.Payload)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to decode payload")
+	}
+	if err = c.verify(env, payload); err != nil {
+		return nil, err
+	}
+
+	var st statement
+	if err = json.Unmarshal(payload, &st); err != nil {
+		return nil, oops.Wrapf(err, "failed to decode in-toto statement")
+	}
+	// The predicate type can be versioned, e.g. https://openvex.dev/ns/v0.2.0
+	if !strings.HasPrefix(st.PredicateType, openVEXPredicateType) || len(st.Predicate) == 0 {
+		return nil, nil
+	}
+	if !st.hasSubject(image.DigestStr()) {
+		return nil, oops.With("digest", image.DigestStr()).Errorf("attestation subject does not match the image")
+	}
+	return st.Predicate, nil
+}
+
+// hasSubject reports whether the statement is about the artifact of the digest, e.g. "sha256:<hex>".
+func (st statement) hasSubject(digest string) bool {
+	alg, encoded, ok := strings.Cut(digest, ":")
+	if !ok || alg != "sha256" {
+		return false
+	}
+	for _, s := range st.Subject {
+		if s.Digest["sha256"] == encoded {
+			return true
+		}
+	}
+	return false
+}
+
+// verify checks if any signature of the envelope is made by any of the public keys.
+func (c *Crawler) verify(env envelope, payload []byte) error {
+	if len(c.publicKeys) == 0 {
+		return nil
+	}
+
+	msg := pae(env.PayloadType, payload)
+	for _, s := range env.Signatures {
+		sig, err := decodeBase64(s.Sig)
+		if err != nil {
+			continue
+		}
+		for _, key := range c.publicKeys {
+			if verifySignature(key, msg, sig) {
+				return nil
+			}
+		}
+	}
+	return oops.Errorf("no valid signature found")
+}
+
+// pae returns the pre-authentication encoding of DSSE
+// cf. https://github.com/secure-systems-lab/dsse/blob/master/protocol.md
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
+
+func verifySignature(key crypto.PublicKey, msg, sig []byte) bool {
+	digest := sha256.Sum256(msg)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest[:], sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, sig)
+	}
+	return false
+}
+
+// decodeBase64 accepts both standard and URL-safe encodings as DSSE allows either.
+func decodeBase64(s string) ([]byte, error) {
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
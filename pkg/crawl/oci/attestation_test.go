@@ -0,0 +1,210 @@
+package oci_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+const (
+	dsseArtifactType     = "application/vnd.dsse.envelope.v1+json"
+	inTotoPayloadType    = "application/vnd.in-toto+json"
+	openVEXPredicateType = "https://openvex.dev/ns/v0.2.0"
+	openVEXPredicate     = `{"@context":"https://openvex.dev/ns/v0.2.0","@id":"https://example.com/vex"}`
+)
+
+func TestCrawler_FetchVEX_Attestations(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	img := newImage(t, nil, nil)
+	imgDigest, err := img.Digest()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, repo name.Repository) []vex.Document
+		opts    []oci.Option
+		wantErr string
+	}{
+		{
+			name: "cosign attestation tag",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				return []vex.Document{
+					pushCosignAttestation(t, repo, img, newEnvelope(t, key, imgDigest, openVEXPredicateType, openVEXPredicate)),
+				}
+			},
+		},
+		{
+			name: "referrers with verification",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				doc := attach(t, repo, img, dsseArtifactType, newEnvelope(t, key, imgDigest, openVEXPredicateType, openVEXPredicate))
+				doc.Content = []byte(openVEXPredicate)
+				return []vex.Document{doc}
+			},
+			opts: []oci.Option{oci.WithPublicKeys(key.Public())},
+		},
+		{
+			name: "signed by an unknown key",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushCosignAttestation(t, repo, img, newEnvelope(t, otherKey, imgDigest, openVEXPredicateType, openVEXPredicate))
+				return nil
+			},
+			opts: []oci.Option{oci.WithPublicKeys(key.Public())},
+		},
+		{
+			name: "other predicate types are ignored",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushCosignAttestation(t, repo, img, newEnvelope(t, key, imgDigest, "https://slsa.dev/provenance/v1", `{}`))
+				return nil
+			},
+		},
+		{
+			name: "statements about other images are skipped",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				other := v1.Hash{Algorithm: "sha256", Hex: strings.Repeat("0", 64)}
+				pushCosignAttestation(t, repo, img, newEnvelope(t, key, other, openVEXPredicateType, openVEXPredicate))
+				return nil
+			},
+		},
+		{
+			name: "envelope over the size limit",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushCosignAttestation(t, repo, img, newEnvelope(t, key, imgDigest, openVEXPredicateType, openVEXPredicate))
+				return nil
+			},
+			opts:    []oci.Option{oci.WithMaxDocumentSize(100)},
+			wantErr: "too large document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(registry.New(
+				registry.Logger(log.New(io.Discard, "", 0)),
+				registry.WithReferrersSupport(true),
+			))
+			t.Cleanup(ts.Close)
+
+			repo, err := name.NewRepository(strings.TrimPrefix(ts.URL, "http://") + "/aquasecurity/trivy")
+			require.NoError(t, err)
+			pushSubject(t, repo.Tag("latest"), img)
+			want := tt.setup(t, repo)
+
+			pkg := config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeOCI,
+					Name: "trivy",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: repo.Name(),
+						},
+					},
+				},
+			}
+
+			crawler := oci.NewCrawler(tt.opts...)
+			got, err := crawler.FetchVEX(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.ElementsMatch(t, want, got)
+		})
+	}
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+	require.NoError(t, err)
+
+	got, err := oci.LoadPublicKey(path)
+	require.NoError(t, err)
+	require.True(t, key.PublicKey.Equal(got))
+}
+
+// newEnvelope returns a DSSE envelope of an in-toto statement about the subject signed by the key.
+func newEnvelope(t *testing.T, key *ecdsa.PrivateKey, subject v1.Hash, predicateType, predicate string) string {
+	payload := fmt.Sprintf(`{"_type":"https://in-toto.io/Statement/v1","subject":[{"name":"trivy","digest":{%q:%q}}],`+
+		`"predicateType":%q,"predicate":%s}`, subject.Algorithm, subject.Hex, predicateType, predicate)
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(inTotoPayloadType), inTotoPayloadType, len(payload), payload)
+	digest := sha256.Sum256([]byte(pae))
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(map[string]any{
+		"payloadType": inTotoPayloadType,
+		"payload":     base64.StdEncoding.EncodeToString([]byte(payload)),
+		"signatures": []map[string]string{
+			{"sig": base64.StdEncoding.EncodeToString(sig)},
+		},
+	})
+	require.NoError(t, err)
+	return string(b)
+}
+
+// pushCosignAttestation pushes the envelope to the `.att` tag of the subject as cosign does,
+// and returns the document expected to be fetched.
+func pushCosignAttestation(t *testing.T, repo name.Repository, subject v1.Image, env string) vex.Document {
+	subjectDigest, err := subject.Digest()
+	require.NoError(t, err)
+
+	layer := static.NewLayer([]byte(env), dsseArtifactType)
+	att, err := mutate.Append(empty.Image, mutate.Addendum{Layer: layer})
+	require.NoError(t, err)
+	att = mutate.MediaType(att, types.OCIManifestSchema1)
+
+	tag := repo.Tag(strings.Replace(subjectDigest.String(), ":", "-", 1) + ".att")
+	require.NoError(t, remote.Write(tag, att))
+
+	digest, err := att.Digest()
+	require.NoError(t, err)
+	layerDigest, err := layer.Digest()
+	require.NoError(t, err)
+	return vex.Document{
+		Name:    layerDigest.Hex + ".openvex.json",
+		Content: []byte(openVEXPredicate),
+		URL:     repo.Digest(digest.String()).Name(),
+		Digest:  digest.String(),
+	}
+}
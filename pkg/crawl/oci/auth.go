@@ -0,0 +1,46 @@
+package oci
+
+import (
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/samber/oops"
+)
+
+// StaticKeychain resolves explicitly configured credentials per registry host, e.g. "index.docker.io".
+// Other registries are anonymous, so that credentials are sent only to their registries.
+type StaticKeychain map[string]authn.AuthConfig
+
+func (k StaticKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if cfg, ok := k[target.RegistryStr()]; ok {
+		return authn.FromConfig(cfg), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// normalizeRegistry returns the registry host as go-containerregistry sees it,
+// e.g. "docker.io" => "index.docker.io".
+func normalizeRegistry(registry string) (string, error) {
+	reg, err := name.NewRegistry(registry)
+	if err != nil {
+		return "", oops.With("registry", registry).Wrapf(err, "parsing registry")
+	}
+	return reg.RegistryStr(), nil
+}
+
+// mirrorRefs returns the references to try in order: mirrors of the registry, and then the original.
+func (c *Crawler) mirrorRefs(ref name.Reference) []name.Reference {
+	var refs []name.Reference
+	for _, mirror := range c.mirrors[ref.Context().RegistryStr()] {
+		repo, err := name.NewRepository(mirror + "/" + ref.Context().RepositoryStr())
+		if err != nil {
+			continue
+		}
+		switch r := ref.(type) {
+		case name.Tag:
+			refs = append(refs, repo.Tag(r.TagStr()))
+		case name.Digest:
+			refs = append(refs, repo.Digest(r.DigestStr()))
+		}
+	}
+	return append(refs, ref)
+}
@@ -2,27 +2,171 @@ package oci
 
 import (
 	"context"
+	"crypto"
+	"errors"
 	"log/slog"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/samber/oops"
 
 	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
 	"github.com/aquasecurity/vexhub-crawler/pkg/url"
 )
 
-const imageSourceAnnotation = "org.opencontainers.image.source"
+const (
+	imageSourceAnnotation   = "org.opencontainers.image.source"
+	imageRevisionAnnotation = "org.opencontainers.image.revision"
+	imageRefNameAnnotation  = "org.opencontainers.image.ref.name"
 
-type Crawler struct{}
+	// Legacy labels of Label Schema
+	// cf. http://label-schema.org/rc1/
+	labelSchemaVCSURL = "org.label-schema.vcs-url"
+	labelSchemaVCSRef = "org.label-schema.vcs-ref"
+)
+
+// imageSource is the source repository the image was built from
+type imageSource struct {
+	url      string
+	revision string // Commit or tag, empty if unknown
+}
+
+var defaultPlatform = v1.Platform{
+	OS:           "linux",
+	Architecture: "amd64",
+}
+
+type Crawler struct {
+	platform    v1.Platform
+	layoutPath  string
+	keychain    authn.Keychain
+	credentials StaticKeychain
+	mirrors     map[string][]string
+	publicKeys  []crypto.PublicKey
+
+	maxDocumentSize int64
+}
+
+type Option func(*Crawler)
+
+// WithPlatform sets the platform of the image to inspect in multi-platform image indexes.
+func WithPlatform(p v1.Platform) Option {
+	return func(c *Crawler) {
+		c.platform = p
+	}
+}
+
+// WithLayout makes the crawler read images from the OCI image layout instead of registries.
+// Images are looked up by the `org.opencontainers.image.ref.name` annotation.
+// cf. https://github.com/opencontainers/image-spec/blob/main/image-layout.md
+func WithLayout(path string) Option {
+	return func(c *Crawler) {
+		c.layoutPath = path
+	}
+}
+
+// WithKeychain replaces the default keychain, which reads the Docker config file and credential helpers.
+func WithKeychain(k authn.Keychain) Option {
+	return func(c *Crawler) {
+		c.keychain = k
+	}
+}
+
+// WithCredentials sets credentials per registry host, which take precedence over the keychain.
+func WithCredentials(creds map[string]authn.AuthConfig) Option {
+	return func(c *Crawler) {
+		for registry, cfg := range creds {
+			if reg, err := normalizeRegistry(registry); err == nil {
+				registry = reg
+			}
+			c.credentials[registry] = cfg
+		}
+	}
+}
 
-func NewCrawler() *Crawler {
-	return &Crawler{}
+// WithMirrors sets mirrors per registry host, e.g. "docker.io" => ["mirror.gcr.io"].
+// Mirrors are tried in order before the original registry.
+func WithMirrors(mirrors map[string][]string) Option {
+	return func(c *Crawler) {
+		for registry, m := range mirrors {
+			if reg, err := normalizeRegistry(registry); err == nil {
+				registry = reg
+			}
+			c.mirrors[registry] = append(c.mirrors[registry], m...)
+		}
+	}
+}
+
+// WithMaxDocumentSize limits the size of artifact layers and attestations read from registries. Zero means no limit.
+func WithMaxDocumentSize(size int64) Option {
+	return func(c *Crawler) {
+		c.maxDocumentSize = size
+	}
 }
 
-func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*url.URL, error) {
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		platform:        defaultPlatform,
+		keychain:        authn.DefaultKeychain,
+		credentials:     StaticKeychain{},
+		mirrors:         map[string][]string{},
+		maxDocumentSize: vex.DefaultLimits.MaxDocumentSize,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*url.URL, error) {
 	errBuilder := oops.Code("crawl_error").In("oci").With("purl", pkg.PURL.String())
+	ref, err := reference(pkg)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "parsing reference")
+	}
+	errBuilder = errBuilder.With("ref", ref.Name())
+
+	idx, img, err := c.fetch(ctx, ref)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "reading image")
+	}
+
+	var src *imageSource
+	var digest v1.Hash
+	if idx != nil {
+		src, err = c.findIndexSource(idx)
+		if err == nil {
+			digest, err = idx.Digest()
+		}
+	} else {
+		src, err = c.findImageSource(img)
+		if err == nil {
+			digest, err = img.Digest()
+		}
+	}
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "finding image source")
+	}
+
+	u, err := url.Parse(src.url)
+	if err != nil {
+		return nil, errBuilder.With("url", src.url).Wrapf(err, "normalizing URL")
+	}
+	// Pin the revision so that VEX documents match the image
+	if src.revision != "" {
+		u.SetRef(src.revision)
+	}
+	u.SetDigest(digest.String())
+
+	return u, nil
+}
+
+// reference returns the image reference of the PURL, e.g. ghcr.io/aquasecurity/trivy:latest
+func reference(pkg config.Package) (name.Reference, error) {
 	qs := pkg.PURL.Qualifiers.Map()
 	repositoryURL, ok := qs["repository_url"]
 	if !ok {
@@ -34,56 +178,170 @@ func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*url.URL, er
 	}
 
 	refStr := repositoryURL + ":" + tag
-	errBuilder = errBuilder.With("ref", refStr)
 	ref, err := name.ParseReference(refStr)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "parsing reference")
+		return nil, oops.With("ref", refStr).Wrapf(err, "parsing reference")
+	}
+	return ref, nil
+}
+
+// fetch returns either the image index or the image the reference points to.
+func (c *Crawler) fetch(ctx context.Context, ref name.Reference) (v1.ImageIndex, v1.Image, error) {
+	if c.layoutPath != "" {
+		return c.fetchFromLayout(ref)
 	}
 
-	img, err := remote.Image(ref)
+	desc, err := c.getDescriptor(ctx, ref)
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "reading image")
+		return nil, nil, oops.Wrapf(err, "getting descriptor")
+	}
+
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, nil, oops.Wrapf(err, "reading image index")
+		}
+		return idx, nil, nil
 	}
 
-	src, err := c.findImageSource(img)
+	img, err := desc.Image()
 	if err != nil {
-		return nil, errBuilder.Wrapf(err, "finding image source")
+		return nil, nil, oops.Wrapf(err, "reading image")
+	}
+	return nil, img, nil
+}
+
+// getDescriptor gets the descriptor from the registry mirrors first, and then from the registry.
+func (c *Crawler) getDescriptor(ctx context.Context, ref name.Reference) (*remote.Descriptor, error) {
+	var errs []error
+	for _, r := range c.mirrorRefs(ref) {
+		desc, err := remote.Get(r, c.remoteOptions(ctx)...)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if r != ref {
+			slog.Info("Using registry mirror", slog.String("ref", ref.Name()), slog.String("mirror", r.Name()))
+		}
+		return desc, nil
 	}
+	return nil, errors.Join(errs...)
+}
 
-	u, err := url.Parse(src)
+func (c *Crawler) remoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.NewMultiKeychain(c.credentials, c.keychain)),
+	}
+}
+
+// fetchFromLayout looks up the reference in index.json of the OCI image layout.
+// `org.opencontainers.image.ref.name` can be either the tag or the full reference.
+func (c *Crawler) fetchFromLayout(ref name.Reference) (v1.ImageIndex, v1.Image, error) {
+	errBuilder := oops.With("layout", c.layoutPath)
+	root, err := layout.ImageIndexFromPath(c.layoutPath)
 	if err != nil {
-		return nil, errBuilder.With("url", src).Wrapf(err, "normalizing URL")
+		return nil, nil, errBuilder.Wrapf(err, "reading OCI layout")
 	}
 
-	return u, nil
+	m, err := root.IndexManifest()
+	if err != nil {
+		return nil, nil, errBuilder.Wrapf(err, "reading index.json")
+	}
+
+	for _, desc := range m.Manifests {
+		refName := desc.Annotations[imageRefNameAnnotation]
+		if refName != ref.Identifier() && refName != ref.Name() && desc.Digest.String() != ref.Identifier() {
+			continue
+		}
+		switch {
+		case desc.MediaType.IsIndex():
+			idx, err := root.ImageIndex(desc.Digest)
+			if err != nil {
+				return nil, nil, errBuilder.Wrapf(err, "reading image index")
+			}
+			return idx, nil, nil
+		case desc.MediaType.IsImage():
+			img, err := root.Image(desc.Digest)
+			if err != nil {
+				return nil, nil, errBuilder.Wrapf(err, "reading image")
+			}
+			return nil, img, nil
+		}
+	}
+	return nil, nil, errBuilder.With("ref", ref.Name()).Errorf("image not found in OCI layout")
+}
+
+// findIndexSource looks for the source in the index annotations first,
+// and then in the image for the configured platform.
+func (c *Crawler) findIndexSource(idx v1.ImageIndex) (*imageSource, error) {
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, oops.Wrapf(err, "reading index manifest")
+	}
+
+	if src, key := lookupSource(m.Annotations); src != nil {
+		slog.Info("Found index annotation", slog.String("annotation", key),
+			slog.String("value", src.url), slog.String("revision", src.revision))
+		return src, nil
+	}
+
+	for _, desc := range m.Manifests {
+		if !desc.MediaType.IsImage() || desc.Platform == nil || !desc.Platform.Satisfies(c.platform) {
+			continue
+		}
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			return nil, oops.With("digest", desc.Digest.String()).Wrapf(err, "reading image")
+		}
+		return c.findImageSource(img)
+	}
+
+	return nil, oops.With("platform", c.platform.String()).Errorf("no image found for platform")
 }
 
-func (c *Crawler) findImageSource(img v1.Image) (string, error) {
+func (c *Crawler) findImageSource(img v1.Image) (*imageSource, error) {
 	// First, try labels in config
 	cfg, err := img.ConfigFile()
 	if err != nil {
-		return "", oops.Wrapf(err, "reading config")
+		return nil, oops.Wrapf(err, "reading config")
 	}
 
-	src, ok := cfg.Config.Labels[imageSourceAnnotation]
-	if ok {
-		slog.Info("Found image label", slog.String("label", imageSourceAnnotation),
-			slog.String("value", src))
+	if src, key := lookupSource(cfg.Config.Labels); src != nil {
+		slog.Info("Found image label", slog.String("label", key),
+			slog.String("value", src.url), slog.String("revision", src.revision))
 		return src, nil
 	}
 
 	// Next, try annotations in manifest
 	m, err := img.Manifest()
 	if err != nil {
-		return "", oops.Wrapf(err, "reading manifest")
+		return nil, oops.Wrapf(err, "reading manifest")
 	}
 
-	src, ok = m.Annotations[imageSourceAnnotation]
-	if ok {
-		slog.Info("Found image annotation", slog.String("annotation", imageSourceAnnotation),
-			slog.String("value", src))
+	if src, key := lookupSource(m.Annotations); src != nil {
+		slog.Info("Found image annotation", slog.String("annotation", key),
+			slog.String("value", src.url), slog.String("revision", src.revision))
 		return src, nil
 	}
 
-	return "", oops.With("annotation", imageSourceAnnotation).Errorf("annotation not found")
+	return nil, oops.With("annotation", imageSourceAnnotation).Errorf("annotation not found")
+}
+
+// lookupSource returns the source in the annotations or labels and the key it was found with.
+// The revision is taken from the same convention as the source, i.e. OCI or Label Schema.
+func lookupSource(values map[string]string) (*imageSource, string) {
+	if src, ok := values[imageSourceAnnotation]; ok {
+		return &imageSource{
+			url:      src,
+			revision: values[imageRevisionAnnotation],
+		}, imageSourceAnnotation
+	}
+	if src, ok := values[labelSchemaVCSURL]; ok {
+		return &imageSource{
+			url:      src,
+			revision: values[labelSchemaVCSRef],
+		}, labelSchemaVCSURL
+	}
+	return nil, ""
 }
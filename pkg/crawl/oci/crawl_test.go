@@ -2,13 +2,29 @@ package oci_test
 
 import (
 	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
 	"github.com/package-url/packageurl-go"
 	"github.com/stretchr/testify/require"
 
@@ -16,6 +32,12 @@ import (
 	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
 )
 
+const (
+	imageSourceAnnotation   = "org.opencontainers.image.source"
+	imageRevisionAnnotation = "org.opencontainers.image.revision"
+	imageRefNameAnnotation  = "org.opencontainers.image.ref.name"
+)
+
 func TestCrawler_DetectSrc(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -160,3 +182,369 @@ func TestCrawler_DetectSrc(t *testing.T) {
 		})
 	}
 }
+
+func TestCrawler_DetectSrc_ImageIndex(t *testing.T) {
+	amd64 := newImage(t, map[string]string{imageSourceAnnotation: "https://github.com/example/amd64"}, nil)
+	arm64 := newImage(t, nil, map[string]string{imageSourceAnnotation: "https://github.com/example/arm64"})
+	idx := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex),
+		mutate.IndexAddendum{
+			Add: amd64,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: "amd64"},
+			},
+		},
+		mutate.IndexAddendum{
+			Add: arm64,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"},
+			},
+		},
+	)
+	annotatedIdx := mutate.Annotations(idx, map[string]string{
+		imageSourceAnnotation: "https://github.com/example/index",
+	}).(v1.ImageIndex)
+
+	// OCI image layout
+	layoutDir := t.TempDir()
+	p, err := layout.Write(layoutDir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendIndex(idx, layout.WithAnnotations(map[string]string{
+		imageRefNameAnnotation: "latest",
+	})))
+	require.NoError(t, p.AppendIndex(annotatedIdx, layout.WithAnnotations(map[string]string{
+		imageRefNameAnnotation: "annotated",
+	})))
+	require.NoError(t, p.AppendImage(amd64, layout.WithAnnotations(map[string]string{
+		imageRefNameAnnotation: "ghcr.io/aquasecurity/trivy:single",
+	})))
+
+	// In-process registry
+	ts := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(ts.Close)
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(u.Host + "/aquasecurity/trivy:latest")
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(ref, idx))
+
+	tests := []struct {
+		name     string
+		registry string
+		tag      string
+		opts     []oci.Option
+		want     string
+		wantErr  string
+	}{
+		{
+			name: "index annotation",
+			tag:  "annotated",
+			opts: []oci.Option{oci.WithLayout(layoutDir)},
+			want: "https://github.com/example/index",
+		},
+		{
+			name: "default platform",
+			opts: []oci.Option{oci.WithLayout(layoutDir)},
+			want: "https://github.com/example/amd64",
+		},
+		{
+			name: "configured platform",
+			opts: []oci.Option{
+				oci.WithLayout(layoutDir),
+				oci.WithPlatform(v1.Platform{OS: "linux", Architecture: "arm64"}),
+			},
+			want: "https://github.com/example/arm64",
+		},
+		{
+			name: "single image with full reference name",
+			tag:  "single",
+			opts: []oci.Option{oci.WithLayout(layoutDir)},
+			want: "https://github.com/example/amd64",
+		},
+		{
+			name:     "index in registry",
+			registry: u.Host,
+			opts: []oci.Option{
+				oci.WithPlatform(v1.Platform{OS: "linux", Architecture: "arm64"}),
+			},
+			want: "https://github.com/example/arm64",
+		},
+		{
+			name: "sad path - platform not found",
+			opts: []oci.Option{
+				oci.WithLayout(layoutDir),
+				oci.WithPlatform(v1.Platform{OS: "linux", Architecture: "s390x"}),
+			},
+			wantErr: "no image found for platform",
+		},
+		{
+			name:    "sad path - tag not found in layout",
+			tag:     "missing",
+			opts:    []oci.Option{oci.WithLayout(layoutDir)},
+			wantErr: "image not found in OCI layout",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := "ghcr.io/aquasecurity/trivy"
+			if tt.registry != "" {
+				repo = tt.registry + "/aquasecurity/trivy"
+			}
+			pkg := config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeOCI,
+					Name: "trivy",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: repo,
+						},
+					},
+				},
+			}
+			if tt.tag != "" {
+				pkg.PURL.Qualifiers = append(pkg.PURL.Qualifiers, packageurl.Qualifier{
+					Key:   "tag",
+					Value: tt.tag,
+				})
+			}
+
+			crawler := oci.NewCrawler(tt.opts...)
+			got, err := crawler.DetectSrc(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func TestCrawler_DetectSrc_Revision(t *testing.T) {
+	images := map[string]v1.Image{
+		"oci": newImage(t, map[string]string{
+			imageSourceAnnotation:   "https://github.com/example/oci",
+			imageRevisionAnnotation: "5a7fdc5a72af148c82128cf07e3b668ffd347454",
+		}, nil),
+		"label-schema": newImage(t, map[string]string{
+			"org.label-schema.vcs-url": "https://github.com/example/label-schema",
+			"org.label-schema.vcs-ref": "c6f4ca18997c1e9f0f8842bb2bf42b9fe81dd737",
+		}, nil),
+		"no-revision": newImage(t, nil, map[string]string{
+			imageSourceAnnotation: "https://github.com/example/no-revision",
+		}),
+	}
+
+	layoutDir := t.TempDir()
+	p, err := layout.Write(layoutDir, empty.Index)
+	require.NoError(t, err)
+	for tag, img := range images {
+		require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+			imageRefNameAnnotation: tag,
+		})))
+	}
+
+	tests := []struct {
+		name    string
+		tag     string
+		want    string
+		wantRef string
+	}{
+		{
+			name:    "OCI annotations",
+			tag:     "oci",
+			want:    "https://github.com/example/oci",
+			wantRef: "5a7fdc5a72af148c82128cf07e3b668ffd347454",
+		},
+		{
+			name:    "Label Schema",
+			tag:     "label-schema",
+			want:    "https://github.com/example/label-schema",
+			wantRef: "c6f4ca18997c1e9f0f8842bb2bf42b9fe81dd737",
+		},
+		{
+			name: "no revision",
+			tag:  "no-revision",
+			want: "https://github.com/example/no-revision",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeOCI,
+					Name: "trivy",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "ghcr.io/aquasecurity/trivy",
+						},
+						{
+							Key:   "tag",
+							Value: tt.tag,
+						},
+					},
+				},
+			}
+
+			crawler := oci.NewCrawler(oci.WithLayout(layoutDir))
+			got, err := crawler.DetectSrc(context.Background(), pkg)
+			require.NoError(t, err)
+
+			digest, err := images[tt.tag].Digest()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, digest.String(), got.Digest())
+		})
+	}
+}
+
+func newImage(t *testing.T, labels, annotations map[string]string) v1.Image {
+	img, err := random.Image(100, 1)
+	require.NoError(t, err)
+
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg = cfg.DeepCopy()
+	cfg.Config.Labels = labels
+
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+	if annotations != nil {
+		img = mutate.Annotations(img, annotations).(v1.Image)
+	}
+	return img
+}
+
+func TestCrawler_DetectSrc_Registry(t *testing.T) {
+	// Ignore the Docker config of the host
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	basicAuth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	logger := registry.Logger(log.New(io.Discard, "", 0))
+
+	private := httptest.NewServer(basicAuth(registry.New(logger)))
+	t.Cleanup(private.Close)
+	privateHost := strings.TrimPrefix(private.URL, "http://")
+	pushImage(t, privateHost+"/aquasecurity/private:latest", "https://github.com/example/private",
+		remote.WithAuth(&authn.Basic{Username: "user", Password: "pass"}))
+
+	mirror := httptest.NewServer(registry.New(logger))
+	t.Cleanup(mirror.Close)
+	mirrorHost := strings.TrimPrefix(mirror.URL, "http://")
+	pushImage(t, mirrorHost+"/aquasecurity/mirrored:latest", "https://github.com/example/mirrored")
+
+	// Registry that is not reachable
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+	unreachableHost := strings.TrimPrefix(unreachable.URL, "http://")
+
+	tests := []struct {
+		name         string
+		repo         string
+		dockerConfig string
+		opts         []oci.Option
+		want         string
+		wantErr      string
+	}{
+		{
+			name: "credentials from config",
+			repo: privateHost + "/aquasecurity/private",
+			opts: []oci.Option{
+				oci.WithCredentials(map[string]authn.AuthConfig{
+					privateHost: {Username: "user", Password: "pass"},
+				}),
+			},
+			want: "https://github.com/example/private",
+		},
+		{
+			name: "credentials from Docker config",
+			repo: privateHost + "/aquasecurity/private",
+			dockerConfig: fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`,
+				privateHost, base64.StdEncoding.EncodeToString([]byte("user:pass"))),
+			want: "https://github.com/example/private",
+		},
+		{
+			name: "registry mirror",
+			repo: unreachableHost + "/aquasecurity/mirrored",
+			opts: []oci.Option{
+				oci.WithMirrors(map[string][]string{
+					unreachableHost: {mirrorHost},
+				}),
+			},
+			want: "https://github.com/example/mirrored",
+		},
+		{
+			name: "fall back to the registry if the mirror doesn't have the image",
+			repo: privateHost + "/aquasecurity/private",
+			opts: []oci.Option{
+				oci.WithMirrors(map[string][]string{
+					privateHost: {mirrorHost},
+				}),
+				oci.WithCredentials(map[string]authn.AuthConfig{
+					privateHost: {Username: "user", Password: "pass"},
+				}),
+			},
+			want: "https://github.com/example/private",
+		},
+		{
+			name:    "sad path - no credentials",
+			repo:    privateHost + "/aquasecurity/private",
+			wantErr: "401 Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.dockerConfig != "" {
+				dir := t.TempDir()
+				err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(tt.dockerConfig), 0600)
+				require.NoError(t, err)
+				t.Setenv("DOCKER_CONFIG", dir)
+			}
+
+			pkg := config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeOCI,
+					Name: path.Base(tt.repo),
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: tt.repo,
+						},
+					},
+				},
+			}
+
+			crawler := oci.NewCrawler(tt.opts...)
+			got, err := crawler.DetectSrc(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
+
+func pushImage(t *testing.T, refStr, src string, opts ...remote.Option) {
+	ref, err := name.ParseReference(refStr)
+	require.NoError(t, err)
+	img := newImage(t, map[string]string{imageSourceAnnotation: src}, nil)
+	require.NoError(t, remote.Write(ref, img, opts...))
+}
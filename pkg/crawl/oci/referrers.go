@@ -0,0 +1,183 @@
+package oci
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+const openVEXArtifactType = "application/vnd.openvex+json"
+
+// FetchVEX fetches OpenVEX documents attached to the image as OCI artifacts or in-toto attestations.
+// The referrers API is used, or the referrers tag schema if the registry doesn't support it.
+// For image indexes, artifacts attached to the image for the configured platform are also fetched.
+// cf. https://github.com/opencontainers/distribution-spec/blob/main/spec.md#listing-referrers
+func (c *Crawler) FetchVEX(ctx context.Context, pkg config.Package) ([]vex.Document, error) {
+	errBuilder := oops.Code("crawl_error").In("oci").With("purl", pkg.PURL.String())
+	if c.layoutPath != "" {
+		// Referrers are not looked up in OCI image layouts
+		return nil, nil
+	}
+
+	ref, err := reference(pkg)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "parsing reference")
+	}
+	errBuilder = errBuilder.With("ref", ref.Name())
+
+	desc, err := c.getDescriptor(ctx, ref)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "getting descriptor")
+	}
+
+	subjects := []v1.Hash{desc.Digest}
+	if desc.MediaType.IsIndex() {
+		idx, err := desc.ImageIndex()
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "reading image index")
+		}
+		m, err := idx.IndexManifest()
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "reading index manifest")
+		}
+		for _, d := range m.Manifests {
+			if d.MediaType.IsImage() && d.Platform != nil && d.Platform.Satisfies(c.platform) {
+				subjects = append(subjects, d.Digest)
+				break
+			}
+		}
+	}
+
+	var docs []vex.Document
+	for _, subject := range subjects {
+		d := ref.Context().Digest(subject.String())
+		layers, err := c.referrerLayers(ctx, d, openVEXArtifactType)
+		if err != nil {
+			return nil, errBuilder.With("subject", d.Name()).Wrapf(err, "fetching referrers")
+		}
+		for _, l := range layers {
+			docs = append(docs, l.document(l.content))
+		}
+
+		atts, err := c.fetchAttestations(ctx, d)
+		if err != nil {
+			return nil, errBuilder.With("subject", d.Name()).Wrapf(err, "fetching attestations")
+		}
+		docs = append(docs, atts...)
+	}
+	return docs, nil
+}
+
+// artifactLayer is a layer of an artifact attached to the image.
+type artifactLayer struct {
+	artifact name.Digest
+	digest   v1.Hash
+	content  []byte
+}
+
+// document returns the VEX document in the layer.
+// Each document is named after the layer digest so that it is stable across runs.
+func (l artifactLayer) document(content []byte) vex.Document {
+	return vex.Document{
+		Name:    l.digest.Hex + ".openvex.json",
+		Content: content,
+		URL:     l.artifact.Name(),
+		Digest:  l.artifact.DigestStr(),
+	}
+}
+
+// referrerLayers returns the layers of artifacts of the type referring to the subject.
+func (c *Crawler) referrerLayers(ctx context.Context, subject name.Digest, artifactType string) ([]artifactLayer, error) {
+	opts := append(c.remoteOptions(ctx), remote.WithFilter("artifactType", artifactType))
+	idx, err := remote.Referrers(subject, opts...)
+	if err != nil {
+		return nil, oops.Wrapf(err, "listing referrers")
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, oops.Wrapf(err, "reading referrers")
+	}
+
+	var layers []artifactLayer
+	for _, desc := range m.Manifests {
+		artifact := subject.Context().Digest(desc.Digest.String())
+		slog.Info("Found artifact", slog.String("subject", subject.Name()),
+			slog.String("artifact", artifact.Name()), slog.String("artifact_type", artifactType))
+
+		img, err := remote.Image(artifact, c.remoteOptions(ctx)...)
+		if err != nil {
+			return nil, oops.With("artifact", artifact.Name()).Wrapf(err, "reading artifact")
+		}
+		l, err := readLayers(artifact, img, c.maxDocumentSize)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l...)
+	}
+	return layers, nil
+}
+
+// readLayers reads the layers of the artifact, each up to maxSize bytes. Zero means no limit.
+func readLayers(artifact name.Digest, img v1.Image, maxSize int64) ([]artifactLayer, error) {
+	errBuilder := oops.With("artifact", artifact.Name())
+	mf, err := img.Manifest()
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "reading artifact manifest")
+	}
+
+	var layers []artifactLayer
+	for _, l := range mf.Layers {
+		content, err := readLayer(img, l.Digest, maxSize)
+		if err != nil {
+			return nil, errBuilder.With("layer", l.Digest.String()).Wrapf(err, "reading layer")
+		}
+		layers = append(layers, artifactLayer{
+			artifact: artifact,
+			digest:   l.Digest,
+			content:  content,
+		})
+	}
+	return layers, nil
+}
+
+// readLayer reads the layer without reading more than maxSize bytes, as registries are untrusted.
+func readLayer(img v1.Image, digest v1.Hash, maxSize int64) ([]byte, error) {
+	layer, err := img.LayerByDigest(digest)
+	if err != nil {
+		return nil, err
+	}
+	if maxSize > 0 {
+		if size, err := layer.Size(); err == nil && size > maxSize {
+			return nil, tooLarge(size, maxSize)
+		}
+	}
+	rc, err := layer.Compressed() // Artifact blobs are stored as is
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	if maxSize <= 0 {
+		return io.ReadAll(rc)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxSize+1))
+	if err != nil {
+		return nil, err
+	} else if int64(len(b)) > maxSize {
+		return nil, tooLarge(int64(len(b)), maxSize)
+	}
+	return b, nil
+}
+
+func tooLarge(size, maxSize int64) error {
+	return oops.Code("document_size_limit_exceeded").With("size", size).With("max_size", maxSize).
+		Errorf("too large document")
+}
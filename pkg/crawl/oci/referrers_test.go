@@ -0,0 +1,172 @@
+package oci_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/oci"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vex"
+)
+
+const openVEXArtifactType = "application/vnd.openvex+json"
+
+func TestCrawler_FetchVEX(t *testing.T) {
+	img := newImage(t, nil, nil)
+	arm64 := newImage(t, nil, nil)
+	idx := mutate.AppendManifests(mutate.IndexMediaType(empty.Index, types.OCIImageIndex),
+		mutate.IndexAddendum{
+			Add: arm64,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+		},
+	)
+
+	tests := []struct {
+		name      string
+		referrers bool // Whether the registry supports the referrers API
+		setup     func(t *testing.T, repo name.Repository) []vex.Document
+		opts      []oci.Option
+		wantErr   string
+	}{
+		{
+			name:      "referrers API",
+			referrers: true,
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushSubject(t, repo.Tag("latest"), img)
+				return []vex.Document{
+					attach(t, repo, img, openVEXArtifactType, `{"id": "image"}`),
+				}
+			},
+		},
+		{
+			name: "referrers tag schema",
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushSubject(t, repo.Tag("latest"), img)
+				return []vex.Document{
+					attach(t, repo, img, openVEXArtifactType, `{"id": "image"}`),
+				}
+			},
+		},
+		{
+			name:      "image index and the platform image",
+			referrers: true,
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushSubject(t, repo.Tag("latest"), idx)
+				return []vex.Document{
+					attach(t, repo, idx, openVEXArtifactType, `{"id": "index"}`),
+					attach(t, repo, arm64, openVEXArtifactType, `{"id": "arm64"}`),
+				}
+			},
+			opts: []oci.Option{oci.WithPlatform(v1.Platform{OS: "linux", Architecture: "arm64"})},
+		},
+		{
+			name:      "other artifacts are ignored",
+			referrers: true,
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushSubject(t, repo.Tag("latest"), img)
+				attach(t, repo, img, "application/vnd.example.sbom+json", `{"id": "sbom"}`)
+				return nil
+			},
+		},
+		{
+			name:      "document over the size limit",
+			referrers: true,
+			setup: func(t *testing.T, repo name.Repository) []vex.Document {
+				pushSubject(t, repo.Tag("latest"), img)
+				attach(t, repo, img, openVEXArtifactType, `{"id": "image"}`)
+				return nil
+			},
+			opts:    []oci.Option{oci.WithMaxDocumentSize(10)},
+			wantErr: "too large document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(registry.New(
+				registry.Logger(log.New(io.Discard, "", 0)),
+				registry.WithReferrersSupport(tt.referrers),
+			))
+			t.Cleanup(ts.Close)
+
+			repo, err := name.NewRepository(strings.TrimPrefix(ts.URL, "http://") + "/aquasecurity/trivy")
+			require.NoError(t, err)
+			want := tt.setup(t, repo)
+
+			pkg := config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeOCI,
+					Name: "trivy",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: repo.Name(),
+						},
+					},
+				},
+			}
+
+			crawler := oci.NewCrawler(tt.opts...)
+			got, err := crawler.FetchVEX(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.ElementsMatch(t, want, got)
+		})
+	}
+}
+
+func pushSubject(t *testing.T, tag name.Tag, subject remote.Taggable) {
+	switch s := subject.(type) {
+	case v1.ImageIndex:
+		require.NoError(t, remote.WriteIndex(tag, s))
+	case v1.Image:
+		require.NoError(t, remote.Write(tag, s))
+	}
+}
+
+// attach pushes an artifact referring to the subject and returns the document expected to be fetched.
+func attach(t *testing.T, repo name.Repository, subject partial.Describable, artifactType, content string) vex.Document {
+	subjectDesc, err := partial.Descriptor(subject)
+	require.NoError(t, err)
+
+	layer := static.NewLayer([]byte(content), types.MediaType(artifactType))
+	artifact, err := mutate.Append(empty.Image, mutate.Addendum{Layer: layer})
+	require.NoError(t, err)
+	artifact = mutate.MediaType(artifact, types.OCIManifestSchema1)
+	artifact = mutate.ConfigMediaType(artifact, types.MediaType(artifactType))
+	artifact = mutate.Subject(artifact, *subjectDesc).(v1.Image)
+
+	digest, err := artifact.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(repo.Digest(digest.String()), artifact))
+
+	layerDigest, err := layer.Digest()
+	require.NoError(t, err)
+	return vex.Document{
+		Name:    layerDigest.Hex + ".openvex.json",
+		Content: []byte(content),
+		URL:     repo.Digest(digest.String()).Name(),
+		Digest:  digest.String(),
+	}
+}
@@ -0,0 +1,51 @@
+package oci
+
+import (
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeOCI, func(conf config.Crawlers) (registry.Crawler, error) {
+		return newFromConfig(conf.OCI, WithMaxDocumentSize(conf.MaxDocumentSize))
+	})
+}
+
+func newFromConfig(conf config.OCI, opts ...Option) (*Crawler, error) {
+	if conf.Platform != "" {
+		platform, err := v1.ParsePlatform(conf.Platform)
+		if err != nil {
+			return nil, oops.With("platform", conf.Platform).Wrapf(err, "failed to parse platform")
+		}
+		opts = append(opts, WithPlatform(*platform))
+	}
+	if conf.Layout != "" {
+		opts = append(opts, WithLayout(conf.Layout))
+	}
+
+	creds := make(map[string]authn.AuthConfig)
+	for _, c := range conf.Credentials {
+		creds[c.Registry] = authn.AuthConfig{
+			Username:      os.ExpandEnv(c.Username),
+			Password:      os.ExpandEnv(c.Password),
+			RegistryToken: os.ExpandEnv(c.Token),
+		}
+	}
+	opts = append(opts, WithCredentials(creds), WithMirrors(conf.Mirrors))
+
+	for _, path := range conf.PublicKeys {
+		key, err := LoadPublicKey(path)
+		if err != nil {
+			return nil, oops.Wrapf(err, "failed to load public key")
+		}
+		opts = append(opts, WithPublicKeys(key))
+	}
+	return NewCrawler(opts...), nil
+}
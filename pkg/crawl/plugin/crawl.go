@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const defaultTimeout = time.Minute
+
+// Request is written to stdin of the plugin as JSON.
+type Request struct {
+	PURL       string            `json:"purl"`
+	Type       string            `json:"type"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Name       string            `json:"name"`
+	Qualifiers map[string]string `json:"qualifiers,omitempty"`
+	Subpath    string            `json:"subpath,omitempty"`
+}
+
+// Response is read from stdout of the plugin as JSON.
+type Response struct {
+	URL     string `json:"url"`
+	Ref     string `json:"ref,omitempty"`     // Branch, tag or commit to check out
+	Subdirs string `json:"subdirs,omitempty"` // Subdirectories to search for VEX documents
+}
+
+// Crawler delegates source detection to an external executable.
+type Crawler struct {
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+type Option func(*Crawler)
+
+// WithArgs sets arguments passed to the command.
+func WithArgs(args ...string) Option {
+	return func(c *Crawler) {
+		c.args = args
+	}
+}
+
+// WithTimeout sets the time limit of the command. Defaults to 1 minute.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Crawler) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+func NewCrawler(command string, opts ...Option) *Crawler {
+	crawler := &Crawler{
+		command: command,
+		timeout: defaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("plugin").With("purl", pkg.PURL.String()).With("command", c.command)
+
+	req, err := json.Marshal(Request{
+		PURL:       pkg.PURL.String(),
+		Type:       pkg.PURL.Type,
+		Namespace:  pkg.PURL.Namespace,
+		Name:       pkg.PURL.Name,
+		Qualifiers: pkg.PURL.Qualifiers.Map(),
+		Subpath:    pkg.PURL.Subpath,
+	})
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to encode request")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.command, c.args...)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Don't wait for grandchildren holding the pipes after the timeout
+	cmd.WaitDelay = time.Second
+
+	if err = cmd.Run(); err != nil {
+		errBuilder = errBuilder.With("stderr", strings.TrimSpace(stderr.String()))
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, errBuilder.Wrapf(err, "plugin timed out after %s", c.timeout)
+		}
+		return nil, errBuilder.Wrapf(err, "plugin failed")
+	}
+
+	var resp Response
+	if err = json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, errBuilder.With("stdout", stdout.String()).Wrapf(err, "failed to decode response")
+	}
+	if resp.URL == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(resp.URL)
+	if err != nil {
+		return nil, errBuilder.With("src", resp.URL).Wrapf(err, "failed to normalize URL")
+	}
+	if resp.Ref != "" {
+		u.SetRef(resp.Ref)
+	}
+	if resp.Subdirs != "" {
+		u.SetSubdirs(resp.Subdirs)
+	}
+	return u, nil
+}
@@ -0,0 +1,106 @@
+package plugin_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/plugin"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins in testdata are shell scripts")
+	}
+
+	pkg := config.Package{
+		PURL: packageurl.PackageURL{
+			Type:      "internal",
+			Namespace: "acme",
+			Name:      "widget",
+		},
+	}
+	tests := []struct {
+		name        string
+		command     string
+		opts        []plugin.Option
+		want        string
+		wantRef     string
+		wantSubdirs string
+		wantErr     string
+	}{
+		{
+			name:        "happy path",
+			command:     "detect.sh",
+			want:        "https://github.com/example/widget",
+			wantRef:     "v1.0.0",
+			wantSubdirs: "vex",
+		},
+		{
+			name:    "sad path with failed plugin",
+			command: "fail.sh",
+			wantErr: "plugin failed",
+		},
+		{
+			name:    "sad path with timeout",
+			command: "slow.sh",
+			opts:    []plugin.Option{plugin.WithTimeout(100 * time.Millisecond)},
+			wantErr: "plugin timed out after 100ms",
+		},
+		{
+			name:    "sad path with bad response",
+			command: "bad.sh",
+			wantErr: "failed to decode response",
+		},
+		{
+			name:    "sad path with empty response",
+			command: "empty.sh",
+			wantErr: "source URL not found",
+		},
+		{
+			name:    "sad path with missing command",
+			command: "missing.sh",
+			wantErr: "no such file or directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := plugin.NewCrawler(filepath.Join("testdata", tt.command), tt.opts...)
+			got, err := crawler.DetectSrc(context.Background(), pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, tt.wantSubdirs, got.Subdirs())
+		})
+	}
+}
+
+func TestCrawler_DetectSrc_Stderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins in testdata are shell scripts")
+	}
+
+	crawler := plugin.NewCrawler(filepath.Join("testdata", "fail.sh"))
+	_, err := crawler.DetectSrc(context.Background(), config.Package{
+		PURL: packageurl.PackageURL{
+			Type: "internal",
+			Name: "widget",
+		},
+	})
+	require.Error(t, err)
+	// stderr is attached to the error context
+	require.Contains(t, fmt.Sprintf("%+v", err), "artifact store unavailable")
+}
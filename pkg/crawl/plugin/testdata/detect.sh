@@ -0,0 +1,4 @@
+#!/bin/sh
+# Resolves the repository from the name in the request
+name=$(sed -n 's/.*"name":"\([^"]*\)".*/\1/p')
+printf '{"url":"https://github.com/example/%s","ref":"v1.0.0","subdirs":"vex"}' "$name"
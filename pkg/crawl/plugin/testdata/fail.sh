@@ -0,0 +1,3 @@
+#!/bin/sh
+echo "artifact store unavailable" >&2
+exit 1
@@ -0,0 +1,103 @@
+package pub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const pubURL = "https://pub.dev"
+
+// Response represents the package metadata of the hosted pub repository
+// cf. https://github.com/dart-lang/pub/blob/master/doc/repository-spec-v2.md#list-all-versions-of-a-package
+type Response struct {
+	Latest struct {
+		Version string `json:"version"`
+		Pubspec struct {
+			Repository string `json:"repository"`
+			Homepage   string `json:"homepage"`
+		} `json:"pubspec"`
+	} `json:"latest"`
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the hosted pub repository.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		c.url = url
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: pubURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("pub").With("purl", pkg.PURL.String())
+	// "pub" type doesn't have namespace, and `repository_url` overrides the hosted repository
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#pub
+	baseURL := c.url
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		baseURL = repositoryURL
+	}
+
+	// Default url format is `https://pub.dev/api/packages/<package-name>`
+	pubURL, err := url.JoinPath(baseURL, "api", "packages", pkg.PURL.Name)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build package url")
+	}
+
+	errBuilder = errBuilder.With("url", pubURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pubURL, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/vnd.pub.v2+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get package info")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get package info: %s", resp.Status)
+	}
+
+	var r Response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode response")
+	}
+
+	// `repository` was introduced later, and older packages put the repository in `homepage`
+	// cf. https://dart.dev/tools/pub/pubspec#repository
+	pubspec := r.Latest.Pubspec
+	src := pubspec.Repository
+	if src == "" {
+		src = pubspec.Homepage
+	}
+	if src == "" {
+		return nil, errBuilder.With("version", r.Latest.Version).Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
@@ -0,0 +1,115 @@
+package pub_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/pub"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name        string
+		pkg         config.Package
+		want        string
+		wantSubdirs string
+		wantErr     string
+	}{
+		{
+			name: "happy path with repository",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "http",
+				},
+			},
+			want:        "https://github.com/dart-lang/http",
+			wantSubdirs: "pkgs/http",
+		},
+		{
+			name: "happy path with homepage",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "path_provider",
+				},
+			},
+			want: "https://github.com/flutter/plugins",
+		},
+		{
+			name: "happy path with repository_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "http",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "{{server}}/private",
+						},
+					},
+				},
+			},
+			want: "https://github.com/example/http",
+		},
+		{
+			name: "sad path when pubspec doesn't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with bad response json",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "bad",
+				},
+			},
+			wantErr: "failed to decode response",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypePub,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get package info: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+			t.Cleanup(ts.Close)
+
+			for i, q := range tt.pkg.PURL.Qualifiers {
+				tt.pkg.PURL.Qualifiers[i].Value = strings.ReplaceAll(q.Value, "{{server}}", ts.URL)
+			}
+
+			crawler := pub.NewCrawler(pub.WithURL(ts.URL))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantSubdirs, got.Subdirs())
+		})
+	}
+}
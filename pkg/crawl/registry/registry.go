@@ -0,0 +1,55 @@
+// Package registry holds crawlers per package type.
+// Built-in crawlers register themselves when pkg/crawl is imported,
+// and other Go code can add or replace crawlers through Register, e.g. in main.
+package registry
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// Crawler detects the source repository of the package.
+type Crawler interface {
+	DetectSrc(context.Context, config.Package) (*url.URL, error)
+}
+
+// Factory builds the crawler from the crawler settings in the config file.
+// Settings of third-party crawlers are available in config.Crawlers.Others.
+type Factory func(config.Crawlers) (Crawler, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register registers the factory for the package type, e.g. "npm".
+// The factory already registered for the type is replaced.
+func Register(typ string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[typ] = factory
+}
+
+// Lookup returns the factory registered for the package type.
+func Lookup(typ string) (Factory, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	f, ok := factories[typ]
+	return f, ok
+}
+
+// Types returns the registered package types in sorted order.
+func Types() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	types := make([]string, 0, len(factories))
+	for typ := range factories {
+		types = append(types, typ)
+	}
+	slices.Sort(types)
+	return types
+}
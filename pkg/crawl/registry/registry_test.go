@@ -0,0 +1,45 @@
+package registry_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+	"github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+type fakeCrawler struct {
+	src string
+}
+
+func (c fakeCrawler) DetectSrc(context.Context, config.Package) (*url.URL, error) {
+	return url.Parse(c.src)
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(src string) registry.Factory {
+		return func(config.Crawlers) (registry.Crawler, error) {
+			return fakeCrawler{src: src}, nil
+		}
+	}
+
+	_, ok := registry.Lookup("test-register")
+	require.False(t, ok)
+
+	registry.Register("test-register", factory("https://github.com/example/first"))
+	registry.Register("test-register", factory("https://github.com/example/second"))
+	require.Contains(t, registry.Types(), "test-register")
+
+	f, ok := registry.Lookup("test-register")
+	require.True(t, ok)
+	crawler, err := f(config.Crawlers{})
+	require.NoError(t, err)
+
+	// The latter replaces the former
+	got, err := crawler.DetectSrc(context.Background(), config.Package{})
+	require.NoError(t, err)
+	require.Equal(t, "https://github.com/example/second", got.String())
+}
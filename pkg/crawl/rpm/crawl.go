@@ -0,0 +1,161 @@
+package rpm
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// fedoraURL is the URL template of spec files in Fedora's dist-git
+const fedoraURL = "https://src.fedoraproject.org/rpms/{name}/raw/rawhide/f/{name}.spec"
+
+// macroRegex matches `%{name}`, `%{?name}` and `%name`
+var macroRegex = regexp.MustCompile(`%\{\??([A-Za-z_][A-Za-z0-9_]*)\}|%([A-Za-z_][A-Za-z0-9_]*)`)
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the URL template of spec files, where `{name}` is replaced with the package name.
+// e.g. https://gitlab.com/redhat/centos-stream/rpms/{name}/-/raw/c9s/{name}.spec
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: fedoraURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("rpm").With("purl", pkg.PURL.String())
+	// "rpm" type uses namespace for the vendor, and the name must be the source package having the spec file
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#rpm
+	specURL := strings.ReplaceAll(c.url, "{name}", pkg.PURL.Name)
+	errBuilder = errBuilder.With("url", specURL)
+
+	r, err := download.Open(ctx, specURL)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get spec file")
+	}
+	defer r.Close()
+
+	tags, err := parseSpec(r)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse spec file")
+	}
+
+	// `URL` is often the project website, so the repository hosting `Source0` archives is preferred
+	// cf. https://rpm-software-management.github.io/rpm/manual/spec.html#url
+	if u, ok := xurl.ParseForge(tags["url"]); ok {
+		return u, nil
+	}
+	for _, key := range []string{"source0", "source"} {
+		if u, ok := xurl.ParseForge(tags[key]); ok {
+			return u, nil
+		}
+	}
+	if tags["url"] == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(tags["url"])
+	if err != nil {
+		return nil, errBuilder.With("src", tags["url"]).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
+
+// parseSpec returns the preamble tags of the spec file with lowercase names.
+// Macros defined by `%global` and `%define` and the tags defined earlier are expanded.
+func parseSpec(r io.Reader) (map[string]string, error) {
+	macros := make(map[string]string)
+	tags := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// The preamble ends with the first section
+		if strings.HasPrefix(line, "%description") || strings.HasPrefix(line, "%prep") {
+			break
+		}
+
+		if directive, rest, ok := strings.Cut(line, " "); ok && (directive == "%global" || directive == "%define") {
+			name, value, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			macros[name] = expand(strings.TrimSpace(value), macros)
+			defineForgeMacros(macros)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.ContainsAny(key, " \t%") {
+			continue
+		}
+		key = strings.ToLower(key)
+		value = expand(strings.TrimSpace(value), macros)
+		if _, found := tags[key]; !found {
+			tags[key] = value
+		}
+		// Tags such as Name and Version are available as macros
+		if _, found := macros[key]; !found {
+			macros[key] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oops.Wrap(err)
+	}
+	return tags, nil
+}
+
+// defineForgeMacros defines the macros of go-rpm-macros used in URL and Source if not defined explicitly,
+// as `%gometa` and `%forgemeta` are not evaluated.
+// cf. https://fedora.gitlab.io/sigs/go/go-rpm-macros/
+func defineForgeMacros(macros map[string]string) {
+	if _, ok := macros["forgeurl"]; !ok && macros["goipath"] != "" {
+		macros["forgeurl"] = "https://" + macros["goipath"]
+	}
+	if _, ok := macros["gourl"]; !ok && macros["forgeurl"] != "" {
+		macros["gourl"] = macros["forgeurl"]
+	}
+}
+
+func expand(s string, macros map[string]string) string {
+	// Macros can refer to other macros
+	for range 5 {
+		expanded := macroRegex.ReplaceAllStringFunc(s, func(m string) string {
+			sub := macroRegex.FindStringSubmatch(m)
+			if v, ok := macros[sub[1]+sub[2]]; ok {
+				return v
+			} else if strings.HasPrefix(m, "%{?") {
+				return ""
+			}
+			return m
+		})
+		if expanded == s {
+			break
+		}
+		s = expanded
+	}
+	return s
+}
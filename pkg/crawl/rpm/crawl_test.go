@@ -0,0 +1,102 @@
+package rpm_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/rpm"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	ts := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		url     string // Defaults to the test server
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path with Source0",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeRPM,
+					Namespace: "fedora",
+					Name:      "jq",
+				},
+			},
+			want: "https://github.com/jqlang/jq",
+		},
+		{
+			name: "happy path with macros in local dist-git",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeRPM,
+					Namespace: "fedora",
+					Name:      "golang-github-spf13-cobra",
+				},
+			},
+			url:  "testdata/rpms/{name}/{name}.spec",
+			want: "https://github.com/spf13/cobra",
+		},
+		{
+			name: "happy path with URL",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeRPM,
+					Namespace: "fedora",
+					Name:      "curl",
+				},
+			},
+			want: "https://curl.se/",
+		},
+		{
+			name: "sad path without URL and Source0",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeRPM,
+					Namespace: "fedora",
+					Name:      "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with missed package",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeRPM,
+					Namespace: "fedora",
+					Name:      "missed",
+				},
+			},
+			wantErr: "failed to get: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := tt.url
+			if u == "" {
+				u = ts.URL + "/rpms/{name}/{name}.spec"
+			}
+			crawler := rpm.NewCrawler(rpm.WithURL(u))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package rpm
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeRPM, func(conf config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(WithURL(conf.RPM.URL)), nil
+	})
+}
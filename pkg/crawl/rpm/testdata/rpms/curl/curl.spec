@@ -0,0 +1,10 @@
+Summary: A utility for getting files from remote servers (FTP, HTTP, and others)
+Name: curl
+Version: 8.9.1
+Release: 2%{?dist}
+License: curl
+Source0: https://curl.se/download/%{name}-%{version}.tar.xz
+URL: https://curl.se/
+
+%description
+curl is a command line tool for transferring data with URL syntax.
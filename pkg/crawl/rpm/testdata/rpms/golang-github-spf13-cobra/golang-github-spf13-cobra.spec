@@ -0,0 +1,20 @@
+# Generated by go2rpm 1.9.0
+%bcond_without check
+
+# https://github.com/spf13/cobra
+%global goipath         github.com/spf13/cobra
+%global forgeurl        https://%{goipath}
+Version:                1.8.1
+
+%gometa -f
+
+Name:           golang-github-spf13-cobra
+Release:        %autorelease
+Summary:        Commander for modern Go CLI interactions
+
+License:        Apache-2.0
+URL:            %{gourl}
+Source:         %{gosource}
+
+%description
+%{common_description}
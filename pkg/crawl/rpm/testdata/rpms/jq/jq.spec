@@ -0,0 +1,16 @@
+Name:           jq
+Version:        1.7.1
+Release:        %autorelease
+Summary:        Command-line JSON processor
+
+License:        MIT AND ICU AND CC-BY-3.0
+URL:            https://jqlang.github.io/jq/
+Source0:        https://github.com/jqlang/jq/releases/download/%{name}-%{version}/%{name}-%{version}.tar.gz
+
+BuildRequires:  gcc
+
+%description
+lightweight and flexible command-line JSON processor
+
+%prep
+%autosetup -n %{name}-%{version}
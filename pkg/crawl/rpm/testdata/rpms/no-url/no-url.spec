@@ -0,0 +1,7 @@
+Name:    no-url
+Version: 1.0
+Release: 1%{?dist}
+Source0: no-url.conf
+
+%description
+No URL
@@ -0,0 +1,97 @@
+package rubygems
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+const rubygemsURL = "https://rubygems.org"
+
+// Response represents the gem metadata
+// cf. https://guides.rubygems.org/rubygems-org-api/#gem-methods
+type Response struct {
+	SourceCodeURI string `json:"source_code_uri"`
+	HomepageURI   string `json:"homepage_uri"`
+}
+
+type Crawler struct {
+	url string
+}
+
+type Option func(*Crawler)
+
+// WithURL sets the gem server, e.g. a private gem server compatible with the RubyGems.org API.
+func WithURL(url string) Option {
+	return func(c *Crawler) {
+		if url != "" {
+			c.url = url
+		}
+	}
+}
+
+func NewCrawler(opts ...Option) *Crawler {
+	crawler := &Crawler{
+		url: rubygemsURL,
+	}
+	for _, opt := range opts {
+		opt(crawler)
+	}
+	return crawler
+}
+
+func (c *Crawler) DetectSrc(ctx context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("rubygems").With("purl", pkg.PURL.String())
+	// "gem" type doesn't have namespace, and `repository_url` overrides the gem server
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#gem
+	baseURL := c.url
+	if repositoryURL, ok := pkg.PURL.Qualifiers.Map()["repository_url"]; ok {
+		baseURL = repositoryURL
+	}
+
+	// Default url format is `https://rubygems.org/api/v1/gems/<gem-name>.json`
+	gemURL, err := url.JoinPath(baseURL, "api", "v1", "gems", pkg.PURL.Name+".json")
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to build gem url")
+	}
+
+	errBuilder = errBuilder.With("url", gemURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gemURL, nil)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to get gem info")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errBuilder.Errorf("failed to get gem info: %s", resp.Status)
+	}
+
+	var r Response
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to decode response")
+	}
+
+	// Many gems point the homepage to the repository without setting the source code URI
+	src := r.SourceCodeURI
+	if src == "" {
+		src = r.HomepageURI
+	}
+	if src == "" {
+		return nil, errBuilder.Errorf("source URL not found")
+	}
+
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
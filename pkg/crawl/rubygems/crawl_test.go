@@ -0,0 +1,117 @@
+package rubygems_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/rubygems"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		want    string
+		wantRef string
+		wantErr string
+	}{
+		{
+			name: "happy path with source code uri",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "rails",
+				},
+			},
+			want:    "https://github.com/rails/rails",
+			wantRef: "v7.2.1",
+		},
+		{
+			name: "happy path with homepage uri",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "rake",
+				},
+			},
+			want: "https://github.com/ruby/rake",
+		},
+		{
+			name: "happy path with repository_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "rake",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "repository_url",
+							Value: "{{server}}/private",
+						},
+					},
+				},
+			},
+			want: "https://github.com/example/rake",
+		},
+		{
+			name: "sad path when response doesn't contain source url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "no-url",
+				},
+			},
+			wantErr: "source URL not found",
+		},
+		{
+			name: "sad path with bad response json",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "bad",
+				},
+			},
+			wantErr: "failed to decode response",
+		},
+		{
+			name: "sad path with missed gem",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGem,
+					Name: "missed",
+				},
+			},
+			wantErr: "failed to get gem info: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := http.FileServer(http.Dir("testdata"))
+			ts := httptest.NewServer(fs)
+			t.Cleanup(ts.Close)
+
+			for i, q := range tt.pkg.PURL.Qualifiers {
+				if q.Value == "{{server}}/private" {
+					tt.pkg.PURL.Qualifiers[i].Value = ts.URL + "/private"
+				}
+			}
+
+			crawler := rubygems.NewCrawler(rubygems.WithURL(ts.URL))
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+		})
+	}
+}
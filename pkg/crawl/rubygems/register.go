@@ -0,0 +1,14 @@
+package rubygems
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	registry.Register(packageurl.TypeGem, func(conf config.Crawlers) (registry.Crawler, error) {
+		return NewCrawler(WithURL(conf.RubyGems.URL)), nil
+	})
+}
@@ -0,0 +1,36 @@
+package swift
+
+import (
+	"context"
+	"strings"
+
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// Crawler resolves Swift packages without any registry,
+// as the namespace and name of the PURL are the location of the Git repository.
+type Crawler struct{}
+
+func NewCrawler() *Crawler {
+	return &Crawler{}
+}
+
+func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("swift").With("purl", pkg.PURL.String())
+	// "swift" type uses the host and owner as namespace, e.g. pkg:swift/github.com/apple/swift-argument-parser
+	// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#swift
+	host, owner, _ := strings.Cut(pkg.PURL.Namespace, "/")
+	if host == "" || owner == "" {
+		return nil, errBuilder.Errorf("namespace must be <host>/<owner>")
+	}
+
+	src := "https://" + pkg.PURL.Namespace + "/" + strings.TrimSuffix(pkg.PURL.Name, ".git")
+	u, err := xurl.Parse(src)
+	if err != nil {
+		return nil, errBuilder.With("src", src).Wrapf(err, "failed to normalize URL")
+	}
+	return u, nil
+}
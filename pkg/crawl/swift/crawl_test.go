@@ -0,0 +1,69 @@
+package swift_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/swift"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name    string
+		pkg     config.Package
+		want    string
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeSwift,
+					Namespace: "github.com/apple",
+					Name:      "swift-argument-parser",
+				},
+			},
+			want: "https://github.com/apple/swift-argument-parser",
+		},
+		{
+			name: "happy path with nested group",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeSwift,
+					Namespace: "gitlab.com/example/ios",
+					Name:      "networking.git",
+				},
+			},
+			want: "https://gitlab.com/example/ios/networking",
+		},
+		{
+			name: "sad path without owner",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeSwift,
+					Namespace: "github.com",
+					Name:      "swift-argument-parser",
+				},
+			},
+			wantErr: "namespace must be <host>/<owner>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := swift.NewCrawler()
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+		})
+	}
+}
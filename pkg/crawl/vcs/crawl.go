@@ -0,0 +1,72 @@
+package vcs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// Crawler resolves packages which are identified by their repositories rather than registries,
+// i.e. "github", "bitbucket" and "generic" types.
+type Crawler struct{}
+
+func NewCrawler() *Crawler {
+	return &Crawler{}
+}
+
+func (c *Crawler) DetectSrc(_ context.Context, pkg config.Package) (*xurl.URL, error) {
+	errBuilder := oops.Code("crawl_error").In("vcs").With("purl", pkg.PURL.String())
+
+	var u *xurl.URL
+	var err error
+	switch pkg.PURL.Type {
+	case packageurl.TypeGithub, packageurl.TypeBitbucket:
+		// The namespace is the owner and the name is the repository
+		// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#github
+		if pkg.PURL.Namespace == "" {
+			return nil, errBuilder.Errorf("namespace must be the owner of the repository")
+		}
+		host := "github.com"
+		if pkg.PURL.Type == packageurl.TypeBitbucket {
+			host = "bitbucket.org"
+		}
+		u, err = xurl.Parse("https://" + host + "/" + strings.ToLower(pkg.PURL.Namespace) + "/" + strings.ToLower(pkg.PURL.Name))
+	case packageurl.TypeGeneric:
+		u, err = fromQualifiers(pkg.PURL)
+	default:
+		return nil, errBuilder.Errorf("unsupported package type: %s", pkg.PURL.Type)
+	}
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to detect source repository")
+	}
+
+	if pkg.PURL.Subpath != "" && u.Subdirs() == "" {
+		u.SetSubdirs(pkg.PURL.Subpath)
+	}
+	return u, nil
+}
+
+// fromQualifiers resolves the repository of "generic" packages from `vcs_url`,
+// or from `download_url` if it is hosted on a forge with releases, e.g. GitHub.
+// cf. https://github.com/package-url/purl-spec/blob/b33dda1cf4515efa8eabbbe8e9b140950805f845/PURL-TYPES.rst#generic
+func fromQualifiers(purl packageurl.PackageURL) (*xurl.URL, error) {
+	qualifiers := purl.Qualifiers.Map()
+	if vcsURL, ok := qualifiers["vcs_url"]; ok {
+		return xurl.ParseVCS(vcsURL)
+	}
+
+	downloadURL, ok := qualifiers["download_url"]
+	if !ok {
+		return nil, oops.Errorf("vcs_url or download_url qualifier is required")
+	}
+	// e.g. https://github.com/<owner>/<repo>/releases/download/<tag>/<asset>
+	if u, ok := xurl.ParseForge(downloadURL); ok {
+		return u, nil
+	}
+	return nil, oops.With("download_url", downloadURL).Errorf("repository not found in download_url")
+}
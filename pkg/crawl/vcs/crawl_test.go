@@ -0,0 +1,149 @@
+package vcs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/package-url/packageurl-go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/vcs"
+)
+
+func TestCrawler_DetectSrc(t *testing.T) {
+	tests := []struct {
+		name        string
+		pkg         config.Package
+		want        string
+		wantRef     string
+		wantSubdirs string
+		wantErr     string
+	}{
+		{
+			name: "happy path with github",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeGithub,
+					Namespace: "aquasecurity",
+					Name:      "Trivy",
+				},
+			},
+			want: "https://github.com/aquasecurity/trivy",
+		},
+		{
+			name: "happy path with bitbucket and subpath",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type:      packageurl.TypeBitbucket,
+					Namespace: "atlassian",
+					Name:      "python-bitbucket",
+					Subpath:   "docs",
+				},
+			},
+			want:        "https://bitbucket.org/atlassian/python-bitbucket",
+			wantSubdirs: "docs",
+		},
+		{
+			name: "happy path with generic and vcs_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGeneric,
+					Name: "openssl",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "vcs_url",
+							Value: "git+https://git.openssl.org/openssl.git@openssl-3.3.2",
+						},
+					},
+				},
+			},
+			want:    "https://git.openssl.org/openssl.git",
+			wantRef: "openssl-3.3.2",
+		},
+		{
+			name: "happy path with generic and download_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGeneric,
+					Name: "kubectl-plugin",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "download_url",
+							Value: "https://github.com/example/kubectl-plugin/releases/download/v1.0.0/kubectl-plugin_linux_amd64.tar.gz",
+						},
+					},
+				},
+			},
+			want: "https://github.com/example/kubectl-plugin",
+		},
+		{
+			name: "happy path with generic and download_url in a GitLab subgroup",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGeneric,
+					Name: "example",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "download_url",
+							Value: "https://www.gitlab.com/example/tools/example/-/archive/v1.0.0/example-v1.0.0.tar.gz",
+						},
+					},
+				},
+			},
+			want: "https://gitlab.com/example/tools/example",
+		},
+		{
+			name: "sad path with generic and unknown download_url",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGeneric,
+					Name: "example",
+					Qualifiers: packageurl.Qualifiers{
+						{
+							Key:   "download_url",
+							Value: "https://downloads.example.com/example-1.0.0.tar.gz",
+						},
+					},
+				},
+			},
+			wantErr: "repository not found in download_url",
+		},
+		{
+			name: "sad path with generic without qualifiers",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGeneric,
+					Name: "openssl",
+				},
+			},
+			wantErr: "vcs_url or download_url qualifier is required",
+		},
+		{
+			name: "sad path with github without namespace",
+			pkg: config.Package{
+				PURL: packageurl.PackageURL{
+					Type: packageurl.TypeGithub,
+					Name: "trivy",
+				},
+			},
+			wantErr: "namespace must be the owner of the repository",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crawler := vcs.NewCrawler()
+			got, err := crawler.DetectSrc(context.Background(), tt.pkg)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, tt.wantSubdirs, got.Subdirs())
+		})
+	}
+}
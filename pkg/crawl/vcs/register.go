@@ -0,0 +1,16 @@
+package vcs
+
+import (
+	"github.com/package-url/packageurl-go"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/config"
+	"github.com/aquasecurity/vexhub-crawler/pkg/crawl/registry"
+)
+
+func init() {
+	for _, typ := range []string{packageurl.TypeGithub, packageurl.TypeBitbucket, packageurl.TypeGeneric} {
+		registry.Register(typ, func(config.Crawlers) (registry.Crawler, error) {
+			return NewCrawler(), nil
+		})
+	}
+}
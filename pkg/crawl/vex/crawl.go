@@ -10,6 +10,8 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -23,28 +25,323 @@ import (
 )
 
 var (
+	// ErrNoVEXFile is returned when none of the VEX documents matches the package.
+	ErrNoVEXFile = errors.New("no VEX file found")
+
 	errPURLMismatch = fmt.Errorf("PURL does not match")
 	errNoStatement  = fmt.Errorf("no statements found")
 )
 
-func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl packageurl.PackageURL) error {
-	errBuilder := oops.In("crawl").With("purl", purl.String()).With("url", url)
+// Limits bounds the resources spent on untrusted repositories and archives. Zero means no limit.
+type Limits struct {
+	MaxRepoSize     int64 // Bytes of a repository or an archive, including written and extracted files
+	MaxDepth        int   // Depth of directories walked from the directory of VEX documents
+	MaxFiles        int   // Files and directories visited in the walk, or written from an archive
+	MaxDocumentSize int64 // Bytes of a VEX document
+}
+
+// DefaultLimits are applied unless WithLimits is given.
+var DefaultLimits = Limits{
+	MaxRepoSize:     2 << 30,
+	MaxDepth:        32,
+	MaxFiles:        100000,
+	MaxDocumentSize: 10 << 20,
+}
+
+type options struct {
+	cache  *download.Cache
+	auth   *download.Auth
+	limits Limits
+}
+
+type Option func(*options)
+
+func newOptions(opts []Option) options {
+	o := options{limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCache fetches repositories through the cache of mirrors.
+func WithCache(cache *download.Cache) Option {
+	return func(o *options) {
+		o.cache = cache
+	}
+}
+
+// WithAuth authenticates to repositories with the credentials.
+func WithAuth(auth *download.Auth) Option {
+	return func(o *options) {
+		o.auth = auth
+	}
+}
+
+// WithLimits replaces DefaultLimits.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// CrawlPackage fetches the source repository and stores VEX documents for the package.
+func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl packageurl.PackageURL, opts ...Option) error {
+	repo, err := Fetch(ctx, []*xurl.URL{url}, opts...)
+	if err != nil {
+		return oops.In("crawl").With("purl", purl.String()).Wrap(err)
+	}
+	defer repo.Close()
+
+	return repo.CrawlPackage(vexHubDir, url, purl)
+}
+
+// Repository is a local copy of the source repository, which can be shared by packages in the repository.
+type Repository struct {
+	tmpDir    string
+	dir       string
+	permaLink *url.URL
+	commit    string
+	limits    Limits
+}
+
+// Fetch fetches the files the URLs can point to as VEX documents.
+// The URLs must point to the same repository and ref, while their subdirectories can differ.
+// The repository must be closed after use.
+func Fetch(ctx context.Context, urls []*xurl.URL, opts ...Option) (*Repository, error) {
+	o := newOptions(opts)
+
+	url := urls[0]
+	errBuilder := oops.In("crawl").With("url", url.Sanitized())
+	tmpDir, err := os.MkdirTemp("", "vexhub-crawler-*")
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create a temporary directory")
+	}
+
+	var matches []func(string) bool
+	for _, u := range urls {
+		matches = append(matches, searchPaths(u))
+	}
+	match := func(p string) bool {
+		return slices.ContainsFunc(matches, func(m func(string) bool) bool {
+			return m(p)
+		})
+	}
+
+	dst := filepath.Join(tmpDir, "repo")
+	downloadOpts := []download.Option{
+		download.WithAuth(o.auth),
+		download.WithMaxSize(o.limits.MaxRepoSize),
+		download.WithMaxFiles(o.limits.MaxFiles),
+	}
+	if o.cache != nil {
+		err = o.cache.Sparse(ctx, url.Canonical(), url.GitString(), url.Ref(), dst, match, downloadOpts...)
+	} else {
+		err = download.Sparse(ctx, url.GitString(), url.Ref(), dst, match, downloadOpts...)
+	}
+	if errors.Is(err, download.ErrUnsupported) {
+		// e.g. the server doesn't support object filters, or the ref is an abbreviated commit hash
+		slog.Info("Partial fetch not supported, falling back to a full clone",
+			slog.String("url", url.Sanitized()), slog.Any("error", err))
+		if err = os.RemoveAll(dst); err != nil {
+			os.RemoveAll(tmpDir)
+			return nil, errBuilder.Wrapf(err, "failed to remove the directory")
+		}
+		if err = download.Download(ctx, url.GetterString(), dst, downloadOpts...); err != nil {
+			os.RemoveAll(tmpDir)
+			return nil, errBuilder.Wrapf(err, "download error")
+		}
+	} else if err != nil {
+		os.RemoveAll(tmpDir)
+		return nil, errBuilder.Wrap(err)
+	}
+
+	return &Repository{
+		tmpDir:    tmpDir,
+		dir:       dst,
+		permaLink: githubPermalink(dst),
+		commit:    headCommit(dst),
+		limits:    o.limits,
+	}, nil
+}
+
+// Close removes the local copy.
+func (r *Repository) Close() error {
+	return os.RemoveAll(r.tmpDir)
+}
+
+// CrawlPackage stores VEX documents for the package in the directory the URL points to.
+// Documents are copied so that other packages can use them.
+func (r *Repository) CrawlPackage(vexHubDir string, url *xurl.URL, purl packageurl.PackageURL) error {
+	errBuilder := oops.In("crawl").With("purl", purl.String()).With("url", url.Sanitized())
+	if r.permaLink != nil {
+		errBuilder = errBuilder.With("permalink", r.permaLink.String())
+	}
+
+	root, err := findRoot(r.dir, url)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to find VEX directory")
+	}
+
+	var files []vexFile
+	err = walk(r.dir, root, r.limits, func(filePath string) error {
+		relPath, err := filepath.Rel(r.dir, filePath) // Relative path from the repository root, not from ".vex/"
+		if err != nil {
+			return errBuilder.With("file_path", filePath).Wrapf(err, "failed to get the relative path")
+		}
+
+		files = append(files, vexFile{
+			path:   filePath,
+			name:   relPath,
+			source: fileSource(relPath, url, r.permaLink, r.commit),
+		})
+		return nil
+	})
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to walk the directory")
+	}
+
+	return store(vexHubDir, purl, files, errBuilder)
+}
+
+// CrawlURL stores VEX documents published at the HTTP(S) URL, either as a document or in an archive.
+// Archives are searched for files matching the patterns as repositories are,
+// while a document is validated regardless of the name as it is specified explicitly.
+func CrawlURL(ctx context.Context, vexHubDir, rawurl string, purl packageurl.PackageURL, opts ...Option) error {
+	o := newOptions(opts)
+	errBuilder := oops.In("crawl").With("purl", purl.String())
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to parse URL")
+	}
+	// Credentials must not be recorded
+	sanitized := *u
+	sanitized.User = nil
+	publicURL := sanitized.String()
+	errBuilder = errBuilder.With("url", publicURL)
 	tmpDir, err := os.MkdirTemp("", "vexhub-crawler-*")
 	if err != nil {
 		return errBuilder.Wrapf(err, "failed to create a temporary directory")
 	}
 	defer os.RemoveAll(tmpDir)
 
-	dst := filepath.Join(tmpDir, purl.Name)
-	if err = download.Download(ctx, url.GetterString(), dst); err != nil {
-		return errBuilder.Wrapf(err, "download error")
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = "openvex.json"
+	}
+	f, err := download.Get(ctx, rawurl, filepath.Join(tmpDir, "download", name), download.WithMaxSize(o.limits.MaxRepoSize))
+	if err != nil {
+		return errBuilder.Wrap(err)
 	}
 
-	permaLink := githubPermalink(dst)
-	if permaLink != nil {
-		errBuilder.With("permalink", permaLink.String())
+	format := download.ArchiveFormat(name, f.ContentType)
+	if format == "" {
+		if err = checkDocumentSize(f.Path, o.limits); err != nil {
+			return errBuilder.Wrap(err)
+		}
+		return store(vexHubDir, purl, []vexFile{
+			{
+				path: f.Path,
+				name: publicURL,
+				source: &manifest.Source{
+					Path:   name,
+					URL:    publicURL,
+					Digest: f.Digest,
+				},
+			},
+		}, errBuilder)
+	}
+
+	dir := filepath.Join(tmpDir, "archive")
+	err = download.Extract(format, f.Path, dir,
+		download.WithMaxSize(o.limits.MaxRepoSize), download.WithMaxFiles(o.limits.MaxFiles))
+	if err != nil {
+		return errBuilder.Wrap(err)
+	}
+	var files []vexFile
+	err = walk(dir, dir, o.limits, func(filePath string) error {
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			return err
+		}
+		files = append(files, vexFile{
+			path: filePath,
+			name: relPath,
+			source: &manifest.Source{
+				Path:   filepath.ToSlash(relPath), // Path in the archive
+				URL:    publicURL,
+				Digest: f.Digest,
+			},
+		})
+		return nil
+	})
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to walk the archive")
+	}
+
+	return store(vexHubDir, purl, files, errBuilder)
+}
+
+// Document is a VEX document fetched without the source repository, such as an OCI artifact.
+type Document struct {
+	Name    string // File name
+	Content []byte
+	URL     string // Where the document was fetched from
+	Digest  string // Digest of the artifact containing the document, if any
+}
+
+// CrawlDocuments stores the documents matching the PURL in the same way as CrawlPackage.
+// Documents over the size limit of WithLimits are rejected.
+func CrawlDocuments(vexHubDir string, docs []Document, purl packageurl.PackageURL, opts ...Option) error {
+	o := newOptions(opts)
+	errBuilder := oops.In("crawl").With("purl", purl.String())
+	for _, doc := range docs {
+		if err := documentSize(int64(len(doc.Content)), o.limits); err != nil {
+			return errBuilder.With("name", doc.Name).With("url", doc.URL).Wrap(err)
+		}
+	}
+
+	tmpDir, err := os.MkdirTemp("", "vexhub-crawler-*")
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to create a temporary directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var files []vexFile
+	for i, doc := range docs {
+		// Each document has its own directory as names can be duplicated across artifacts
+		name := filepath.Base(doc.Name)
+		filePath := filepath.Join(tmpDir, strconv.Itoa(i), name)
+		if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return errBuilder.Wrapf(err, "failed to create a directory")
+		}
+		if err = os.WriteFile(filePath, doc.Content, 0644); err != nil {
+			return errBuilder.With("name", doc.Name).Wrapf(err, "failed to write the document")
+		}
+		files = append(files, vexFile{
+			path: filePath,
+			name: doc.URL,
+			source: &manifest.Source{
+				Path:   name,
+				URL:    doc.URL,
+				Digest: doc.Digest,
+			},
+		})
 	}
 
+	return store(vexHubDir, purl, files, errBuilder)
+}
+
+// vexFile is a candidate of VEX documents for the package.
+type vexFile struct {
+	path   string // Path to the local file
+	name   string // Name for logging
+	source *manifest.Source
+}
+
+// store validates the files and copies the ones matching the PURL to the package directory of VEX Hub,
+// along with manifest.json.
+func store(vexHubDir string, purl packageurl.PackageURL, files []vexFile, errBuilder oops.OopsErrorBuilder) error {
 	vexDir := filepath.Join(vexHubDir, "pkg", purl.Type, purl.Namespace, purl.Name, purl.Subpath)
 	if purl.Type == packageurl.TypeOCI {
 		name := purl.Qualifiers.Map()["repository_url"]
@@ -54,60 +351,38 @@ func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl pac
 	errBuilder = errBuilder.With("dir", vexDir)
 
 	// Reset the directory
-	if err = resetDir(vexDir); err != nil {
+	if err := resetDir(vexDir); err != nil {
 		return errBuilder.Wrapf(err, "failed to reset the directory")
 	}
 
 	var found bool
 	var sources []manifest.Source
-	logger := slog.With(slog.String("purl", purl.String()), "url", url)
-
-	root := filepath.Join(dst, url.Subdirs())
-	if _, err := os.Stat(filepath.Join(root, ".vex")); err == nil {
-		root = filepath.Join(root, ".vex") // If the directory contains a .vex directory, use it as the root
-	}
-	err = filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return errBuilder.Wrapf(err, "failed to walk the directory")
-		} else if d.IsDir() {
-			return nil
-		} else if !matchPath(filePath) {
-			return nil
-		}
-
-		relPath, err := filepath.Rel(dst, filePath) // Relative path from the repository root, not from ".vex/"
-		if err != nil {
-			return errBuilder.With("file_path", filePath).Wrapf(err, "failed to get the relative path")
-		}
+	logger := slog.With(slog.String("purl", purl.String()))
 
-		logger.Info("Parsing VEX file", slog.String("path", relPath))
-		if err = validateVEX(filePath, purl.String()); errors.Is(err, errNoStatement) {
-			return errBuilder.With("path", relPath).Wrapf(err, "no statement found")
+	for _, f := range files {
+		logger.Info("Parsing VEX file", slog.String("path", f.name))
+		if err := validateVEX(f.path, purl.String()); errors.Is(err, errNoStatement) {
+			return errBuilder.With("path", f.name).Wrapf(err, "no statement found")
 		} else if errors.Is(err, errPURLMismatch) {
-			logger.Info("PURL does not match", slog.String("path", relPath))
-			return nil
+			logger.Info("PURL does not match", slog.String("path", f.name))
+			continue
 		} else if err != nil {
 			return errBuilder.Wrapf(err, "failed to validate VEX file")
 		}
 
 		found = true
-		to := filepath.Join(vexDir, filepath.Base(filePath))
-		if err = os.Rename(filePath, to); err != nil {
-			return errBuilder.With("from", filePath).With("to", to).Wrapf(err, "failed to rename")
+		to := filepath.Join(vexDir, filepath.Base(f.path))
+		if err := copyFile(f.path, to); err != nil {
+			return errBuilder.With("from", f.path).With("to", to).Wrapf(err, "failed to copy")
 		}
 
-		if src := fileSource(relPath, url, permaLink); src != nil {
-			sources = append(sources, *src)
+		if f.source != nil {
+			sources = append(sources, *f.source)
 		}
-
-		return nil
-	})
-	if err != nil {
-		return errBuilder.Wrapf(err, "failed to walk the directory")
 	}
 
 	if !found {
-		return errBuilder.Errorf("no VEX file found")
+		return errBuilder.Wrap(ErrNoVEXFile)
 	}
 
 	// Check if there are any changes in the VEX directory.
@@ -123,13 +398,183 @@ func CrawlPackage(ctx context.Context, vexHubDir string, url *xurl.URL, purl pac
 		ID:      purl.String(),
 		Sources: sources,
 	}
-	if err = manifest.Write(filepath.Join(vexDir, manifest.FileName), m); err != nil {
+	if err := manifest.Write(filepath.Join(vexDir, manifest.FileName), m); err != nil {
 		return oops.Wrapf(err, "failed to write sources")
 	}
 
 	return nil
 }
 
+func copyFile(from, to string) error {
+	b, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, b, 0644)
+}
+
+// findRoot returns the directory to search for VEX documents.
+// `.vex/` in the module directory is preferred, and then `.vex/` at the repository root.
+// If neither exists, the module directory itself is searched.
+func findRoot(repoDir string, url *xurl.URL) (string, error) {
+	dirs := append([]string{url.Subdirs()}, url.FallbackSubdirs()...)
+	for _, dir := range append(dirs, "") {
+		vexDir := filepath.Join(repoDir, dir, ".vex")
+		if fi, err := os.Stat(vexDir); err == nil && fi.IsDir() {
+			return vexDir, checkWithin(repoDir, vexDir)
+		}
+	}
+
+	for _, dir := range dirs {
+		d := filepath.Join(repoDir, dir)
+		if fi, err := os.Stat(d); err == nil && fi.IsDir() {
+			return d, checkWithin(repoDir, d)
+		}
+	}
+	return "", oops.With("subdirs", url.Subdirs()).Errorf("directory not found")
+}
+
+// checkWithin returns an error if dir resolves outside repoDir through symbolic links.
+func checkWithin(repoDir, dir string) error {
+	errBuilder := oops.Code("path_escape_rejected").With("dir", dir)
+	resolvedRepo, err := filepath.EvalSymlinks(repoDir)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to resolve the repository directory")
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to resolve the directory")
+	}
+	if rel, err := filepath.Rel(resolvedRepo, resolved); err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+		return errBuilder.With("resolved", resolved).Errorf("directory outside the repository")
+	}
+	return nil
+}
+
+// walk calls fn for the files matching the patterns under root in repoDir within the limits.
+// Matched symbolic links and irregular files are rejected rather than skipped, as they can point outside root.
+func walk(repoDir, root string, limits Limits, fn func(filePath string) error) error {
+	var visited int
+	return filepath.WalkDir(root, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		errBuilder := oops.With("file_path", filePath)
+
+		visited++
+		if limits.MaxFiles > 0 && visited > limits.MaxFiles {
+			return errBuilder.Code("file_limit_exceeded").With("max_files", limits.MaxFiles).Errorf("too many files")
+		} else if d.IsDir() {
+			if limits.MaxDepth > 0 && depth(root, filePath) > limits.MaxDepth {
+				return errBuilder.Code("depth_limit_exceeded").With("max_depth", limits.MaxDepth).Errorf("too deep directories")
+			}
+			return nil
+		} else if !matchPath(filePath) {
+			return nil
+		}
+
+		switch {
+		case d.Type()&fs.ModeSymlink != 0:
+			return rejectSymlink(repoDir, filePath)
+		case !d.Type().IsRegular():
+			return errBuilder.Code("irregular_file_rejected").With("mode", d.Type().String()).Errorf("irregular files are not allowed")
+		}
+		if err = checkDocumentSize(filePath, limits); err != nil {
+			return err
+		}
+		return fn(filePath)
+	})
+}
+
+// rejectSymlink returns the error for the matched symbolic link, depending on whether it points outside repoDir.
+// The link is resolved lexically as in sparse fetches, where the target may not be written.
+func rejectSymlink(repoDir, filePath string) error {
+	errBuilder := oops.With("file_path", filePath)
+	target, err := os.Readlink(filePath)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to read the symbolic link")
+	}
+	resolved := target
+	if !filepath.IsAbs(target) {
+		resolved = filepath.Join(filepath.Dir(filePath), target)
+	}
+	if rel, err := filepath.Rel(repoDir, resolved); err != nil || !filepath.IsLocal(rel) {
+		return errBuilder.Code("path_escape_rejected").With("target", target).
+			Errorf("symbolic link resolves outside the repository")
+	}
+	return errBuilder.Code("symlink_rejected").Errorf("symbolic links are not allowed")
+}
+
+// depth returns the number of directories from root to the path.
+func depth(root, filePath string) int {
+	rel, err := filepath.Rel(root, filePath)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+func checkDocumentSize(filePath string, limits Limits) error {
+	if limits.MaxDocumentSize <= 0 {
+		return nil
+	}
+	fi, err := os.Lstat(filePath)
+	if err != nil {
+		return oops.With("file_path", filePath).Wrapf(err, "failed to stat the file")
+	} else if err = documentSize(fi.Size(), limits); err != nil {
+		return oops.With("file_path", filePath).Wrap(err)
+	}
+	return nil
+}
+
+// documentSize returns an error if the size of a document exceeds the limit.
+func documentSize(size int64, limits Limits) error {
+	if limits.MaxDocumentSize > 0 && size > limits.MaxDocumentSize {
+		return oops.Code("document_size_limit_exceeded").With("size", size).
+			With("max_size", limits.MaxDocumentSize).Errorf("too large document")
+	}
+	return nil
+}
+
+// searchPaths returns the matcher of files and directories in the repository that findRoot can search,
+// so that the rest of the repository is not written.
+func searchPaths(url *xurl.URL) func(string) bool {
+	var dirs, roots []string
+	for _, dir := range append([]string{url.Subdirs()}, url.FallbackSubdirs()...) {
+		dir = strings.Trim(dir, "/")
+		dirs = append(dirs, dir)
+		roots = append(roots, path.Join(dir, ".vex"))
+	}
+	roots = append(roots, ".vex")
+
+	within := func(p, dir string) bool {
+		return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+	}
+	return func(p string) bool {
+		if slices.Contains(roots, p) || slices.Contains(dirs, p) {
+			return true
+		} else if !matchPath(p) {
+			return false
+		}
+		return within(p, ".vex") || slices.ContainsFunc(dirs, func(dir string) bool {
+			return within(p, dir)
+		})
+	}
+}
+
+// headCommit returns the commit checked out in the repository, or empty if unknown.
+func headCommit(repoDir string) string {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return ""
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return ""
+	}
+	return head.Hash().String()
+}
+
 func githubPermalink(repoDir string) *url.URL {
 	repo, err := git.PlainOpen(repoDir)
 	if err != nil {
@@ -193,10 +638,12 @@ func validateVEX(path, purl string) error {
 	return errPURLMismatch
 }
 
-func fileSource(relPath string, url *xurl.URL, permaLink *url.URL) *manifest.Source {
+func fileSource(relPath string, url *xurl.URL, permaLink *url.URL, commit string) *manifest.Source {
 	source := manifest.Source{
-		Path: filepath.Base(relPath),
-		URL:  url.String(),
+		Path:   filepath.Base(relPath),
+		URL:    url.Sanitized(),
+		Digest: url.Digest(),
+		Commit: commit,
 	}
 	if permaLink != nil {
 		l := *permaLink
@@ -1,18 +1,28 @@
 package vex_test
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	openvex "github.com/openvex/go-vex/pkg/vex"
 	"github.com/package-url/packageurl-go"
+	"github.com/samber/oops"
 	"github.com/sosedoff/gitkit"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -29,7 +39,8 @@ var signature = &object.Signature{
 }
 
 // NewServer creates a new Git server for testing purposes.
-func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) *httptest.Server {
+// It returns the server and the commit of the repository.
+func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) (*httptest.Server, plumbing.Hash) {
 	wtDir := t.TempDir()
 
 	r, err := git.PlainInit(wtDir, false)
@@ -43,15 +54,21 @@ func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) *httpt
 	_, err = wt.Add(".")
 	require.NoError(t, err)
 
-	_, err = wt.Commit("initial commit", &git.CommitOptions{
+	commit, err := wt.Commit("initial commit", &git.CommitOptions{
 		Author: signature,
 	})
 	require.NoError(t, err)
 
+	// Partial fetches are supported as GitHub does
 	bareDir := t.TempDir()
 	gitDir := filepath.Join(bareDir, repo+".git")
-	_, err = git.PlainClone(gitDir, true, &git.CloneOptions{URL: wtDir})
+	bare, err := git.PlainClone(gitDir, true, &git.CloneOptions{URL: wtDir})
 	require.NoError(t, err)
+	cfg, err := bare.Config()
+	require.NoError(t, err)
+	cfg.Raw.Section("uploadpack").SetOption("allowFilter", "true")
+	cfg.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+	require.NoError(t, bare.SetConfig(cfg))
 
 	service := gitkit.New(gitkit.Config{
 		Dir:        bareDir,
@@ -60,17 +77,21 @@ func NewServer(t *testing.T, repo string, setup func(*testing.T, string)) *httpt
 	})
 
 	// Add logging middleware
-	return httptest.NewServer(service)
+	return httptest.NewServer(service), commit
 }
 
 func TestCrawlPackage(t *testing.T) {
 	tests := []struct {
-		name         string
-		purl         string
-		want         openvex.VEX
-		wantManifest manifest.Manifest
-		wantErr      string
-		setup        func(*testing.T, string) // Additional setup function for complex cases
+		name            string
+		purl            string
+		subdirs         string
+		fallbackSubdirs []string
+		digest          string
+		pinCommit       bool // Check out the commit instead of the default branch
+		want            openvex.VEX
+		wantManifest    manifest.Manifest
+		wantErr         string
+		setup           func(*testing.T, string) // Additional setup function for complex cases
 	}{
 		{
 			name: "valid VEX file",
@@ -183,6 +204,81 @@ func TestCrawlPackage(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "OCI package pinned to the image revision",
+			purl:      "pkg:oci/myimage?repository_url=example.com/repo",
+			digest:    "sha256:53e6715d5c67e80e629f0dfa3bd6ed2bc74bdcaa4bdbe934a5a1811a249db6b9",
+			pinCommit: true,
+			want:      testVEX("pkg:oci/myimage?repository_url=example.com/repo"),
+			wantManifest: manifest.Manifest{
+				ID: "pkg:oci/myimage?repository_url=example.com%2Frepo",
+				Sources: []manifest.Source{
+					{
+						Path:   "openvex.json",
+						Digest: "sha256:53e6715d5c67e80e629f0dfa3bd6ed2bc74bdcaa4bdbe934a5a1811a249db6b9",
+						// URL and Commit will be set dynamically in the test
+					},
+				},
+			},
+		},
+		{
+			name:    "nested module with .vex at the repository root",
+			purl:    "pkg:golang/github.com/example/package/sub@v1.2.3",
+			subdirs: "sub",
+			want:    testVEX("pkg:golang/github.com/example/package/sub@v1.2.3"),
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "sub", "go.mod"), []byte("module github.com/example/package/sub"))
+				writeVEX(t, filepath.Join(dir, ".vex", "openvex.json"), testVEX("pkg:golang/github.com/example/package/sub@v1.2.3"))
+			},
+			wantManifest: manifest.Manifest{
+				ID: "pkg:golang/github.com/example/package/sub@v1.2.3",
+				Sources: []manifest.Source{
+					{Path: "openvex.json"},
+				},
+			},
+		},
+		{
+			name:    ".vex in the module directory is preferred",
+			purl:    "pkg:golang/github.com/example/package/sub@v1.2.3",
+			subdirs: "sub",
+			want:    testVEX("pkg:golang/github.com/example/package/sub@v1.2.3"),
+			setup: func(t *testing.T, dir string) {
+				writeVEX(t, filepath.Join(dir, "sub", ".vex", "openvex.json"), testVEX("pkg:golang/github.com/example/package/sub@v1.2.3"))
+				writeVEX(t, filepath.Join(dir, ".vex", "openvex.json"), testVEX("pkg:golang/github.com/example/package@v1.2.3"))
+			},
+			wantManifest: manifest.Manifest{
+				ID: "pkg:golang/github.com/example/package/sub@v1.2.3",
+				Sources: []manifest.Source{
+					{Path: "openvex.json"},
+				},
+			},
+		},
+		{
+			name:            "major branch",
+			purl:            "pkg:golang/github.com/example/package/v3@v3.0.0",
+			subdirs:         "v3",
+			fallbackSubdirs: []string{""},
+			want:            testVEX("pkg:golang/github.com/example/package/v3@v3.0.0"),
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "go.mod"), []byte("module github.com/example/package/v3"))
+				writeVEX(t, filepath.Join(dir, "openvex.json"), testVEX("pkg:golang/github.com/example/package/v3@v3.0.0"))
+			},
+			wantManifest: manifest.Manifest{
+				ID: "pkg:golang/github.com/example/package/v3@v3.0.0",
+				Sources: []manifest.Source{
+					{Path: "openvex.json"},
+				},
+			},
+		},
+		{
+			name:    "module directory not found",
+			purl:    "pkg:golang/github.com/example/package/sub@v1.2.3",
+			subdirs: "sub",
+			setup: func(t *testing.T, dir string) {
+				writeFile(t, filepath.Join(dir, "README.md"), nil)
+			},
+			wantErr: "directory not found",
+		},
 		{
 			name: "non-matching file",
 			purl: "pkg:golang/github.com/example/package@v1.2.3",
@@ -203,7 +299,7 @@ func TestCrawlPackage(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			vexHubDir := t.TempDir()
 
-			server := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+			server, commit := NewServer(t, "testrepo", func(t *testing.T, dir string) {
 				if tt.setup != nil {
 					tt.setup(t, dir)
 				} else {
@@ -225,6 +321,12 @@ func TestCrawlPackage(t *testing.T) {
 
 			u, err := url.Parse(server.URL + "/testrepo.git")
 			require.NoError(t, err)
+			u.SetSubdirs(tt.subdirs)
+			u.SetFallbackSubdirs(tt.fallbackSubdirs...)
+			u.SetDigest(tt.digest)
+			if tt.pinCommit {
+				u.SetRef(commit.String())
+			}
 
 			err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl)
 			if tt.wantErr != "" {
@@ -272,8 +374,425 @@ func TestCrawlPackage(t *testing.T) {
 			assert.NoError(t, err)
 
 			tt.wantManifest.Sources[0].URL = server.URL + "/testrepo.git"
+			tt.wantManifest.Sources[0].Commit = commit.String()
 
 			assert.Equal(t, tt.wantManifest, gotManifest)
 		})
 	}
 }
+
+func TestCrawlPackage_Limits(t *testing.T) {
+	outsideDir := t.TempDir()
+	writeVEX(t, filepath.Join(outsideDir, "openvex.json"), testVEX("pkg:npm/foo@1.0.0"))
+	// Relative as go-git stores absolute links relative to the worktree
+	outsideLink := strings.Repeat("../", 32) + filepath.ToSlash(outsideDir)
+
+	tests := []struct {
+		name     string
+		limits   vex.Limits
+		setup    func(*testing.T, string)
+		wantCode string
+	}{
+		{
+			name:     "repository too large",
+			limits:   vex.Limits{MaxRepoSize: 100},
+			wantCode: "size_limit_exceeded",
+		},
+		{
+			name:   "too many files",
+			limits: vex.Limits{MaxFiles: 2},
+			setup: func(t *testing.T, dir string) {
+				for _, name := range []string{"a.openvex.json", "b.openvex.json", "c.openvex.json"} {
+					writeVEX(t, filepath.Join(dir, ".vex", name), testVEX("pkg:npm/foo@1.0.0"))
+				}
+			},
+			wantCode: "file_limit_exceeded",
+		},
+		{
+			name:   "too deep directories",
+			limits: vex.Limits{MaxDepth: 1},
+			setup: func(t *testing.T, dir string) {
+				writeVEX(t, filepath.Join(dir, ".vex", "a", "b", "openvex.json"), testVEX("pkg:npm/foo@1.0.0"))
+			},
+			wantCode: "depth_limit_exceeded",
+		},
+		{
+			name:     "document too large",
+			limits:   vex.Limits{MaxDocumentSize: 10},
+			wantCode: "document_size_limit_exceeded",
+		},
+		{
+			name: "symbolic link",
+			setup: func(t *testing.T, dir string) {
+				writeVEX(t, filepath.Join(dir, "docs", "openvex.json"), testVEX("pkg:npm/foo@1.0.0"))
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, ".vex"), 0755))
+				require.NoError(t, os.Symlink("../docs/openvex.json", filepath.Join(dir, ".vex", "openvex.json")))
+			},
+			wantCode: "symlink_rejected",
+		},
+		{
+			name: "symbolic link outside the repository",
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.MkdirAll(filepath.Join(dir, ".vex"), 0755))
+				require.NoError(t, os.Symlink(outsideLink+"/openvex.json", filepath.Join(dir, ".vex", "openvex.json")))
+			},
+			wantCode: "path_escape_rejected",
+		},
+		{
+			name: "directory outside the repository",
+			setup: func(t *testing.T, dir string) {
+				require.NoError(t, os.Symlink(outsideLink, filepath.Join(dir, ".vex")))
+			},
+			wantCode: "path_escape_rejected",
+		},
+	}
+
+	for _, tt := range tests {
+		server, commit := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+			if tt.setup != nil {
+				tt.setup(t, dir)
+				return
+			}
+			writeVEX(t, filepath.Join(dir, ".vex", "openvex.json"), testVEX("pkg:npm/foo@1.0.0"))
+		})
+		t.Cleanup(server.Close)
+
+		// Abbreviated commit hashes can't be fetched partially, so the repository is cloned in full
+		modes := []struct {
+			name string
+			ref  string
+		}{
+			{name: "sparse"},
+			{name: "full clone", ref: commit.String()[:7]},
+		}
+		for _, mode := range modes {
+			t.Run(tt.name+" with "+mode.name, func(t *testing.T) {
+				u, err := url.Parse(server.URL + "/testrepo.git")
+				require.NoError(t, err)
+				u.SetRef(mode.ref)
+				purl, err := packageurl.FromString("pkg:npm/foo@1.0.0")
+				require.NoError(t, err)
+
+				vexHubDir := t.TempDir()
+				err = vex.CrawlPackage(context.Background(), vexHubDir, u, purl, vex.WithLimits(tt.limits))
+				require.Error(t, err)
+				oopsErr, ok := oops.AsOops(err)
+				require.True(t, ok)
+				assert.Equal(t, tt.wantCode, oopsErr.Code())
+				assert.NoFileExists(t, filepath.Join(vexHubDir, "pkg", "npm", "foo", "openvex.json"))
+			})
+		}
+	}
+}
+
+func TestRepository_CrawlPackage(t *testing.T) {
+	server, _ := NewServer(t, "testrepo", func(t *testing.T, dir string) {
+		// Shared by two packages
+		v := testVEX("pkg:npm/foo@1.0.0")
+		v.Statements[0].Products = append(v.Statements[0].Products, openvex.Product{
+			Component: openvex.Component{ID: "pkg:npm/bar@1.0.0"},
+		})
+		writeVEX(t, filepath.Join(dir, ".vex", "openvex.json"), v)
+		writeVEX(t, filepath.Join(dir, "baz", ".vex", "openvex.json"), testVEX("pkg:npm/baz@1.0.0"))
+	})
+	t.Cleanup(server.Close)
+
+	packages := []struct {
+		purl    string
+		subdirs string
+	}{
+		{purl: "pkg:npm/foo@1.0.0"},
+		{purl: "pkg:npm/bar@1.0.0"},
+		{purl: "pkg:npm/baz@1.0.0", subdirs: "baz"},
+	}
+
+	var urls []*url.URL
+	for _, pkg := range packages {
+		u, err := url.Parse(server.URL + "/testrepo.git")
+		require.NoError(t, err)
+		u.SetSubdirs(pkg.subdirs)
+		urls = append(urls, u)
+	}
+
+	repo, err := vex.Fetch(context.Background(), urls)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, repo.Close()) })
+
+	vexHubDir := t.TempDir()
+	for i, pkg := range packages {
+		purl, err := packageurl.FromString(pkg.purl)
+		require.NoError(t, err)
+		require.NoError(t, repo.CrawlPackage(vexHubDir, urls[i], purl))
+
+		got, err := openvex.Open(filepath.Join(vexHubDir, "pkg", "npm", purl.Name, "openvex.json"))
+		require.NoError(t, err)
+		assert.True(t, slices.ContainsFunc(got.Statements[0].Products, func(p openvex.Product) bool {
+			return p.ID == pkg.purl
+		}))
+	}
+}
+
+func TestCrawlDocuments(t *testing.T) {
+	const (
+		digest = "sha256:cd9b4a8b3bb2f3f6fae5a4f0e5db96ba54e4b2a4d2fe6d3c0c5b3c3b6e7e5a41"
+		ref    = "ghcr.io/aquasecurity/trivy@" + digest
+	)
+	purl, err := packageurl.FromString("pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy")
+	require.NoError(t, err)
+
+	match, err := json.Marshal(testVEX("pkg:oci/trivy?repository_url=ghcr.io/aquasecurity/trivy"))
+	require.NoError(t, err)
+	mismatch, err := json.Marshal(testVEX("pkg:oci/other?repository_url=ghcr.io/aquasecurity/other"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name         string
+		docs         []vex.Document
+		limits       vex.Limits
+		wantFiles    []string
+		wantManifest manifest.Manifest
+		wantErr      string
+	}{
+		{
+			name: "matching documents",
+			docs: []vex.Document{
+				{
+					Name:    "aaa.openvex.json",
+					Content: match,
+					URL:     ref,
+					Digest:  digest,
+				},
+				{
+					Name:    "bbb.openvex.json",
+					Content: mismatch,
+					URL:     ref,
+					Digest:  digest,
+				},
+			},
+			wantFiles: []string{"aaa.openvex.json"},
+			wantManifest: manifest.Manifest{
+				ID: purl.String(),
+				Sources: []manifest.Source{
+					{
+						Path:   "aaa.openvex.json",
+						URL:    ref,
+						Digest: digest,
+					},
+				},
+			},
+		},
+		{
+			name: "no matching document",
+			docs: []vex.Document{
+				{
+					Name:    "bbb.openvex.json",
+					Content: mismatch,
+					URL:     ref,
+				},
+			},
+			wantErr: "no VEX file found",
+		},
+		{
+			name: "document too large",
+			docs: []vex.Document{
+				{
+					Name:    "aaa.openvex.json",
+					Content: match,
+					URL:     ref,
+				},
+			},
+			limits:  vex.Limits{MaxDocumentSize: 10},
+			wantErr: "too large document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vexHubDir := t.TempDir()
+			err := vex.CrawlDocuments(vexHubDir, tt.docs, purl, vex.WithLimits(tt.limits))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			dir := filepath.Join(vexHubDir, "pkg", "oci", "ghcr.io", "aquasecurity", "trivy")
+			for _, f := range tt.wantFiles {
+				assert.FileExists(t, filepath.Join(dir, f))
+			}
+
+			got, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantManifest, got)
+		})
+	}
+}
+
+func TestCrawlURL(t *testing.T) {
+	const purl = "pkg:npm/foo@1.0.0"
+	match, err := json.Marshal(testVEX(purl))
+	require.NoError(t, err)
+	mismatch, err := json.Marshal(testVEX("pkg:npm/bar@1.0.0"))
+	require.NoError(t, err)
+	archive := tarGz(t, map[string][]byte{
+		"vex/foo.openvex.json": match,
+		"vex/bar.openvex.json": mismatch,
+		"README.md":            []byte("# VEX"),
+	})
+
+	files := map[string][]byte{
+		"/openvex.json":  match,
+		"/mismatch.json": mismatch,
+		"/vex.tar.gz":    archive,
+		"/download":      archive, // Detected by the content type
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/download" {
+			w.Header().Set("Content-Type", "application/gzip")
+		}
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(ts.Close)
+
+	digest := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantFile   string
+		wantSource manifest.Source
+		wantErr    string
+	}{
+		{
+			name:     "document",
+			path:     "/openvex.json",
+			wantFile: "openvex.json",
+			wantSource: manifest.Source{
+				Path:   "openvex.json",
+				URL:    "{{server}}/openvex.json",
+				Digest: digest(match),
+			},
+		},
+		{
+			name:     "archive",
+			path:     "/vex.tar.gz",
+			wantFile: "foo.openvex.json",
+			wantSource: manifest.Source{
+				Path:   "vex/foo.openvex.json",
+				URL:    "{{server}}/vex.tar.gz",
+				Digest: digest(archive),
+			},
+		},
+		{
+			name:     "archive by content type",
+			path:     "/download",
+			wantFile: "foo.openvex.json",
+			wantSource: manifest.Source{
+				Path:   "vex/foo.openvex.json",
+				URL:    "{{server}}/download",
+				Digest: digest(archive),
+			},
+		},
+		{
+			name:    "PURL mismatch",
+			path:    "/mismatch.json",
+			wantErr: "no VEX file found",
+		},
+		{
+			name:    "not found",
+			path:    "/missed.json",
+			wantErr: "failed to get: 404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := packageurl.FromString(purl)
+			require.NoError(t, err)
+
+			vexHubDir := t.TempDir()
+			err = vex.CrawlURL(context.Background(), vexHubDir, ts.URL+tt.path, p)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			dir := filepath.Join(vexHubDir, "pkg", "npm", "foo")
+			assert.FileExists(t, filepath.Join(dir, tt.wantFile))
+
+			got, err := manifest.Read(filepath.Join(dir, manifest.FileName))
+			require.NoError(t, err)
+			tt.wantSource.URL = strings.ReplaceAll(tt.wantSource.URL, "{{server}}", ts.URL)
+			assert.Equal(t, manifest.Manifest{
+				ID:      purl,
+				Sources: []manifest.Source{tt.wantSource},
+			}, got)
+		})
+	}
+}
+
+func testVEX(productID string) openvex.VEX {
+	return openvex.VEX{
+		Metadata: openvex.Metadata{
+			Context: openvex.ContextLocator(),
+			ID:      "https://example.com/vex-1234",
+			Author:  "Example Corp.",
+			Version: 1,
+		},
+		Statements: []openvex.Statement{
+			{
+				Vulnerability: openvex.Vulnerability{ID: "CVE-2023-1234"},
+				Products: []openvex.Product{
+					{
+						Component: openvex.Component{
+							ID: productID,
+						},
+					},
+				},
+				Status:        openvex.StatusNotAffected,
+				Justification: openvex.VulnerableCodeNotPresent,
+			},
+		},
+	}
+}
+
+func writeVEX(t *testing.T, filePath string, v openvex.VEX) {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	writeFile(t, filePath, b)
+}
+
+func writeFile(t *testing.T, filePath string, content []byte) {
+	err := os.MkdirAll(filepath.Dir(filePath), 0755)
+	require.NoError(t, err)
+	err = os.WriteFile(filePath, content, 0644)
+	require.NoError(t, err)
+}
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0644,
+			Size: int64(len(content)),
+		})
+		require.NoError(t, err)
+		_, err = tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
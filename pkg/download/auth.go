@@ -0,0 +1,199 @@
+package download
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/samber/oops"
+)
+
+// Credential authenticates to repositories on the host.
+// Password, which can be a token, is used for HTTP(S), and SSHKey is used for SSH.
+type Credential struct {
+	Host             string
+	Username         string // Defaults to "git"
+	Password         string
+	SSHKey           string // Path to the private key. The SSH agent is used if empty.
+	SSHKeyPassphrase string
+}
+
+// Auth holds credentials for repositories.
+type Auth struct {
+	Credentials []Credential
+	// KnownHosts is the path to known_hosts to verify SSH hosts with.
+	// Defaults to $SSH_KNOWN_HOSTS or ~/.ssh/known_hosts.
+	KnownHosts string
+}
+
+// credential returns the credential for the host, or nil.
+// Credentials match either the host name alone or the host name with the port, e.g. "git.example.com:8443".
+func (a *Auth) credential(host, port string) *Credential {
+	if a == nil {
+		return nil
+	}
+	for i, c := range a.Credentials {
+		if strings.EqualFold(c.Host, host) || (port != "" && strings.EqualFold(c.Host, net.JoinHostPort(host, port))) {
+			return &a.Credentials[i]
+		}
+	}
+	return nil
+}
+
+// method returns the authentication method of go-git for the repository, or nil to use the defaults.
+func (a *Auth) method(repoURL string) (transport.AuthMethod, error) {
+	if a == nil {
+		return nil, nil
+	}
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to parse the repository URL")
+	}
+	var port string
+	if ep.Port != 0 {
+		port = strconv.Itoa(ep.Port)
+	}
+	c := a.credential(ep.Host, port)
+
+	switch ep.Protocol {
+	case "http", "https":
+		if c == nil || c.Password == "" {
+			return nil, nil
+		}
+		return &githttp.BasicAuth{
+			Username: c.username(),
+			Password: c.Password,
+		}, nil
+	case "ssh":
+		// The user in the URL, e.g. git@github.com:org/repo.git, is overridden only if configured
+		user := ep.User
+		if user == "" || (c != nil && c.Username != "") {
+			user = c.username()
+		}
+		if c != nil && c.SSHKey != "" {
+			keys, err := gitssh.NewPublicKeysFromFile(user, c.SSHKey, c.SSHKeyPassphrase)
+			if err != nil {
+				return nil, oops.With("host", ep.Host).Wrapf(err, "failed to load the SSH key")
+			}
+			if err = a.setKnownHosts(&keys.HostKeyCallbackHelper); err != nil {
+				return nil, err
+			}
+			return keys, nil
+		} else if a.KnownHosts != "" {
+			agent, err := gitssh.NewSSHAgentAuth(user)
+			if err != nil {
+				return nil, oops.Wrapf(err, "failed to connect to the SSH agent")
+			}
+			if err = a.setKnownHosts(&agent.HostKeyCallbackHelper); err != nil {
+				return nil, err
+			}
+			return agent, nil
+		}
+	}
+	return nil, nil
+}
+
+// setKnownHosts verifies hosts with the configured known_hosts. go-git uses the default known_hosts otherwise.
+func (a *Auth) setKnownHosts(h *gitssh.HostKeyCallbackHelper) error {
+	if a.KnownHosts == "" {
+		return nil
+	}
+	callback, err := gitssh.NewKnownHostsCallback(a.KnownHosts)
+	if err != nil {
+		return oops.With("known_hosts", a.KnownHosts).Wrapf(err, "failed to load known_hosts")
+	}
+	h.HostKeyCallback = callback
+	return nil
+}
+
+func (c *Credential) username() string {
+	if c == nil || c.Username == "" {
+		return "git"
+	}
+	return c.Username
+}
+
+// getterString adds the credential to the URL for go-getter, i.e. the user info for HTTP(S),
+// and the "sshkey" parameter for SSH. It also returns the secrets to redact.
+func (a *Auth) getterString(src string) (string, []string, error) {
+	forced, rawurl, found := strings.Cut(src, "::")
+	if !found {
+		forced, rawurl = "", src
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return "", nil, oops.Wrapf(err, "failed to parse URL")
+	}
+	c := a.credential(u.Hostname(), u.Port())
+	if c == nil {
+		return src, nil, nil
+	}
+
+	var secrets []string
+	switch u.Scheme {
+	case "http", "https":
+		if c.Password == "" {
+			return src, nil, nil
+		}
+		u.User = url.UserPassword(c.username(), c.Password)
+		_, escaped, _ := strings.Cut(u.User.String(), ":")
+		secrets = append(secrets, c.Password, escaped)
+	case "ssh":
+		if c.SSHKey == "" {
+			return src, nil, nil
+		} else if c.SSHKeyPassphrase != "" {
+			return "", nil, oops.Errorf("SSH keys with passphrases are not supported in full clones")
+		}
+		key, err := os.ReadFile(c.SSHKey)
+		if err != nil {
+			return "", nil, oops.Wrapf(err, "failed to read the SSH key")
+		}
+		encoded := base64.StdEncoding.EncodeToString(key)
+		q := u.Query()
+		q.Set("sshkey", encoded)
+		u.RawQuery = q.Encode()
+		secrets = append(secrets, encoded, url.QueryEscape(encoded))
+	default:
+		return src, nil, nil
+	}
+
+	if forced != "" {
+		return forced + "::" + u.String(), secrets, nil
+	}
+	return u.String(), secrets, nil
+}
+
+// redact replaces the secrets in the string.
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "REDACTED")
+		}
+	}
+	return s
+}
+
+// redactError returns the error with the secrets redacted from the message.
+func redactError(err error, secrets []string) error {
+	if err == nil || len(secrets) == 0 {
+		return err
+	}
+	return errors.New(redact(err.Error(), secrets))
+}
+
+// redactURL removes the user info from the URL for logs.
+func redactURL(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,121 @@
+package download_test
+
+import (
+	"context"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestSparse_Auth(t *testing.T) {
+	server, _ := newServer(t, true)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		auth    *download.Auth
+		wantErr string
+	}{
+		{
+			name: "happy path",
+			auth: &download.Auth{
+				Credentials: []download.Credential{
+					{
+						Host:     u.Host,
+						Password: "secret",
+					},
+				},
+			},
+		},
+		{
+			name: "host name without the port",
+			auth: &download.Auth{
+				Credentials: []download.Credential{
+					{
+						Host:     u.Hostname(),
+						Password: "secret",
+					},
+				},
+			},
+		},
+		{
+			name: "sad path with credentials for another host",
+			auth: &download.Auth{
+				Credentials: []download.Credential{
+					{
+						Host:     "git.example.com",
+						Password: "secret",
+					},
+				},
+			},
+			wantErr: "authentication required",
+		},
+		{
+			name:    "sad path without credentials",
+			wantErr: "authentication required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := filepath.Join(t.TempDir(), "repo")
+			err := download.Sparse(context.Background(), server.URL+"/repo.git", "", dst, func(string) bool { return true },
+				download.WithAuth(tt.auth))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.FileExists(t, filepath.Join(dst, ".vex", "openvex.json"))
+		})
+	}
+}
+
+func TestDownload_Auth(t *testing.T) {
+	server, _ := newServer(t, true)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{
+			name:     "happy path",
+			password: "secret",
+		},
+		{
+			name:     "sad path with wrong password",
+			password: "wrong-secret",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := &download.Auth{
+				Credentials: []download.Credential{
+					{
+						Host:     u.Host,
+						Password: tt.password,
+					},
+				},
+			}
+			dst := filepath.Join(t.TempDir(), "repo")
+			err := download.Download(context.Background(), "git::"+server.URL+"/repo.git?depth=1", dst, download.WithAuth(auth))
+			if tt.wantErr {
+				require.Error(t, err)
+				require.NotContains(t, err.Error(), tt.password)
+				return
+			}
+			require.NoError(t, err)
+			require.FileExists(t, filepath.Join(dst, "main.go"))
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/samber/oops"
+)
+
+// mirrorRefSpecs fetch branches, tags and the default branch of the repository.
+var mirrorRefSpecs = []config.RefSpec{
+	"+refs/heads/*:refs/heads/*",
+	"+refs/tags/*:refs/tags/*",
+	"+HEAD:refs/remotes/" + git.DefaultRemoteName + "/HEAD",
+}
+
+// Cache keeps bare mirrors of repositories between runs, so that only new objects are fetched.
+// Mirrors are keyed by canonical repository URLs, and the least recently used ones are evicted
+// once the total size exceeds the limit.
+// Mirrors are locked while used, so the cache can be shared by concurrent runs.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// NewCache returns the cache in dir. maxSize is in bytes, and zero means no limit.
+func NewCache(dir string, maxSize int64) *Cache {
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		locks:   make(map[string]*sync.Mutex),
+	}
+}
+
+// Sparse updates the mirror of the repository, and writes the matched files of the commit at the ref to dst in the same way as Sparse.
+// The key is the canonical URL of the repository. WithMaxSize and WithMaxFiles limit only the written files,
+// as mirrors hold the full history and are limited by the size of the cache as a whole.
+func (c *Cache) Sparse(ctx context.Context, key, repoURL, ref, dst string, match func(path string) bool, opts ...Option) error {
+	slog.Info("Fetching to the cache...", slog.String("url", redactURL(repoURL)), slog.String("ref", ref))
+	errBuilder := oops.Code("download_error").In("download").With("url", redactURL(repoURL)).With("ref", ref).With("dst", dst)
+
+	o := newOptions(opts)
+	auth, err := o.auth.method(repoURL)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to set up authentication")
+	}
+
+	mirrorDir := c.mirrorDir(key)
+	errBuilder = errBuilder.With("mirror", mirrorDir)
+	unlock, err := c.lock(mirrorDir, true)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to lock the mirror")
+	}
+	defer unlock()
+
+	mirror, err := openMirror(ctx, mirrorDir, repoURL, auth)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to update the mirror")
+	}
+	// Mark as used for eviction
+	now := time.Now()
+	if err = os.Chtimes(mirrorDir, now, now); err != nil {
+		return errBuilder.Wrapf(err, "failed to update the access time")
+	}
+
+	hash, err := resolveMirrorRef(mirror, ref)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to resolve the ref")
+	}
+	commit, err := peel(mirror, hash)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to get the commit")
+	}
+
+	repo, _, err := initRepo(dst, repoURL)
+	if err != nil {
+		return errBuilder.Wrap(err)
+	}
+	if err = checkout(repo, mirror, commit, dst, match, o.limits()); err != nil {
+		return errBuilder.With("commit", commit.Hash.String()).Wrap(err)
+	}
+	unlock()
+
+	if err = c.evict(mirrorDir); err != nil {
+		slog.Warn("Failed to evict mirrors", slog.Any("error", err))
+	}
+	return nil
+}
+
+// mirrorDir returns the directory of the mirror. Keys are hashed as URLs can't be used as file names as they are.
+func (c *Cache) mirrorDir(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".git")
+}
+
+// openMirror opens the bare mirror in dir, or creates it, and fetches new objects.
+func openMirror(ctx context.Context, dir, repoURL string, auth transport.AuthMethod) (*git.Repository, error) {
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(dir, true)
+	}
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to open the mirror")
+	}
+
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		remote, err = repo.CreateRemote(&config.RemoteConfig{
+			Name:  git.DefaultRemoteName,
+			URLs:  []string{repoURL},
+			Fetch: mirrorRefSpecs,
+		})
+	}
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to get the remote")
+	}
+
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RemoteURL: repoURL,
+		Tags:      git.NoTags,
+		Prune:     true,
+		Auth:      auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil, oops.Wrapf(err, "failed to fetch")
+	}
+	return repo, nil
+}
+
+// resolveMirrorRef returns the hash of the ref in the mirror.
+// Branches take precedence over tags with the same name, as in resolveRef.
+func resolveMirrorRef(repo *git.Repository, ref string) (plumbing.Hash, error) {
+	if ref == "" {
+		r, err := repo.Reference(plumbing.NewRemoteHEADReferenceName(git.DefaultRemoteName), true)
+		if err != nil {
+			return plumbing.ZeroHash, oops.Wrapf(err, "default branch not found")
+		}
+		return r.Hash(), nil
+	}
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)} {
+		if r, err := repo.Reference(name, true); err == nil {
+			return r.Hash(), nil
+		}
+	}
+	// Commits including abbreviated hashes
+	hash, err := repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return plumbing.ZeroHash, oops.Errorf("reference not found: %s", ref)
+	}
+	return *hash, nil
+}
+
+// lock locks the mirror both in the process and across processes.
+// If wait is false, it doesn't block and returns errLocked if the mirror is in use.
+func (c *Cache) lock(mirrorDir string, wait bool) (func(), error) {
+	c.mu.Lock()
+	mu, ok := c.locks[mirrorDir]
+	if !ok {
+		mu = &sync.Mutex{}
+		c.locks[mirrorDir] = mu
+	}
+	c.mu.Unlock()
+
+	if !wait && !mu.TryLock() {
+		return nil, errLocked
+	} else if wait {
+		mu.Lock()
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		mu.Unlock()
+		return nil, oops.Wrapf(err, "failed to create the cache directory")
+	}
+	unlockFile, err := lockFile(mirrorDir+".lock", wait)
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			unlockFile()
+			mu.Unlock()
+		})
+	}, nil
+}
+
+// evict removes the least recently used mirrors until the total size is within the limit.
+// Mirrors in use are skipped, and the mirror just used is kept even if it alone exceeds the limit.
+func (c *Cache) evict(keep string) error {
+	if c.maxSize <= 0 {
+		return nil
+	}
+
+	type mirror struct {
+		dir    string
+		size   int64
+		usedAt time.Time
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return oops.Wrapf(err, "failed to read the cache directory")
+	}
+	var mirrors []mirror
+	var total int64
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return oops.Wrapf(err, "failed to stat the mirror")
+		}
+		dir := filepath.Join(c.dir, entry.Name())
+		size, err := dirSize(dir)
+		if err != nil {
+			return oops.With("mirror", dir).Wrapf(err, "failed to get the size")
+		}
+		mirrors = append(mirrors, mirror{dir: dir, size: size, usedAt: info.ModTime()})
+		total += size
+	}
+
+	slices.SortFunc(mirrors, func(a, b mirror) int {
+		return a.usedAt.Compare(b.usedAt)
+	})
+	for _, m := range mirrors {
+		if total <= c.maxSize {
+			break
+		} else if m.dir == keep {
+			continue
+		}
+		unlock, err := c.lock(m.dir, false)
+		if errors.Is(err, errLocked) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		slog.Info("Evicting the mirror", slog.String("mirror", m.dir), slog.Int64("size", m.size))
+		err = os.RemoveAll(m.dir)
+		unlock()
+		if err != nil {
+			return oops.With("mirror", m.dir).Wrapf(err, "failed to remove the mirror")
+		}
+		total -= m.size
+	}
+	return nil
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		} else if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	return size, err
+}
@@ -11,9 +11,18 @@ import (
 )
 
 // Download downloads the configured source to the destination.
-func Download(ctx context.Context, src, dst string) error {
-	slog.Info("Downloading...", slog.String("src", src))
-	errBuilder := oops.Code("download_error").In("download").With("src", src).With("dst", dst)
+// Credentials are added to the source for go-getter, and redacted from logs and errors.
+// WithMaxSize is checked once downloaded, as go-getter can't stop downloads in the middle.
+func Download(ctx context.Context, src, dst string, opts ...Option) error {
+	o := newOptions(opts)
+	src, secrets, err := o.auth.getterString(src)
+	if err != nil {
+		return oops.Code("download_error").In("download").With("dst", dst).Wrapf(err, "failed to set up authentication")
+	}
+	redacted := redact(src, secrets)
+
+	slog.Info("Downloading...", slog.String("src", redacted))
+	errBuilder := oops.Code("download_error").In("download").With("src", redacted).With("dst", dst)
 
 	pwd, err := os.Getwd()
 	if err != nil {
@@ -31,7 +40,10 @@ func Download(ctx context.Context, src, dst string) error {
 	}
 
 	if err = client.Get(); err != nil {
-		return errBuilder.Wrapf(err, "download error")
+		return errBuilder.Wrapf(redactError(err, secrets), "download error")
+	}
+	if err = checkSize(dst, o.maxSize); err != nil {
+		return errBuilder.Wrap(err)
 	}
 
 	return nil
@@ -0,0 +1,187 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hashicorp/go-getter"
+	"github.com/samber/oops"
+
+	xurl "github.com/aquasecurity/vexhub-crawler/pkg/url"
+)
+
+// archiveExts are extensions of archives, which are also their formats in go-getter.
+// Compressed single files, such as "gz", are not archives of documents.
+var archiveExts = []string{"tar.gz", "tgz", "tar.bz2", "tbz2", "tar.xz", "txz", "tar.zst", "tzst", "tar", "zip"}
+
+// archiveTypes maps content types of archives to their formats. Compressed files are assumed to be tarballs.
+var archiveTypes = map[string]string{
+	"application/gzip":             "tar.gz",
+	"application/x-gzip":           "tar.gz",
+	"application/x-compressed-tar": "tar.gz",
+	"application/x-bzip2":          "tar.bz2",
+	"application/x-xz":             "tar.xz",
+	"application/zstd":             "tar.zst",
+	"application/x-tar":            "tar",
+	"application/zip":              "zip",
+	"application/x-zip-compressed": "zip",
+}
+
+// File is a file downloaded with Get.
+type File struct {
+	Path        string
+	Digest      string // SHA-256 digest of the content, e.g. "sha256:..."
+	ContentType string
+}
+
+// IsFile reports whether the HTTP(S) URL points to a VEX document or an archive of documents rather than a repository.
+// URLs are judged by the extension, and by the content type in the response to a HEAD request if they have no extension.
+func IsFile(ctx context.Context, rawurl string) bool {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	if _, ok := xurl.ParseForge(rawurl); ok && !isForgeDownload(u) {
+		return false // Forges serve web pages of repositories and files, e.g. /<owner>/<repo>/blob/main/vex.json
+	}
+
+	name := strings.TrimSuffix(u.Path, "/")
+	if ArchiveFormat(name, "") != "" || isDocument(name, "") {
+		return true
+	} else if path.Ext(name) != "" {
+		return false // e.g. ".git"
+	}
+
+	resp, err := get(ctx, http.MethodHead, rawurl)
+	if err != nil {
+		slog.Debug("Failed to detect the content type", slog.String("url", redactURL(rawurl)), slog.Any("error", err))
+		return false
+	}
+	resp.Body.Close()
+	contentType := resp.Header.Get("Content-Type")
+	return ArchiveFormat("", contentType) != "" || isDocument("", contentType)
+}
+
+// isForgeDownload reports whether the URL on a forge serves the file as it is rather than its web page,
+// e.g. /<owner>/<repo>/raw/..., /<owner>/<repo>/releases/download/..., /<group>/<repo>/-/archive/... or ?raw=true.
+func isForgeDownload(u *url.URL) bool {
+	if u.Query().Get("raw") == "true" {
+		return true
+	}
+
+	// Endpoints follow /<owner>/<repo> on forges, or "/-/" on GitLab where repositories can be nested in subgroups
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	i := 2
+	if strings.TrimPrefix(u.Host, "www.") == "gitlab.com" {
+		i = slices.Index(parts, "-") + 1
+		if i == 0 {
+			i = 2
+		}
+	}
+	if i >= len(parts) {
+		return false
+	}
+
+	switch parts[i] {
+	case "raw", "archive", "downloads", "uploads", "attachments":
+		return true
+	case "releases":
+		// e.g. /releases/download/<tag>/<asset> on GitHub, /-/releases/<tag>/downloads/<asset> on GitLab
+		return slices.Contains(parts[i+1:], "download") || slices.Contains(parts[i+1:], "downloads")
+	}
+	return false
+}
+
+// Get downloads the file at the HTTP(S) URL to dst. WithMaxSize limits the size of the file.
+func Get(ctx context.Context, rawurl, dst string, opts ...Option) (*File, error) {
+	o := newOptions(opts)
+	slog.Info("Downloading...", slog.String("url", redactURL(rawurl)))
+	errBuilder := oops.Code("download_error").In("download").With("url", redactURL(rawurl)).With("dst", dst)
+
+	resp, err := get(ctx, http.MethodGet, rawurl)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if o.maxSize > 0 && resp.ContentLength > o.maxSize {
+		return nil, errBuilder.Wrap(tooLarge(resp.ContentLength, o.maxSize))
+	}
+
+	if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create a directory")
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to create the file")
+	}
+	defer f.Close()
+
+	var body io.Reader = resp.Body
+	if o.maxSize > 0 {
+		// Read one more byte to detect files over the limit without the length
+		body = io.LimitReader(resp.Body, o.maxSize+1)
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), body)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to download")
+	} else if o.maxSize > 0 && n > o.maxSize {
+		return nil, errBuilder.Wrap(tooLarge(n, o.maxSize))
+	}
+	if err = f.Close(); err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to close the file")
+	}
+
+	return &File{
+		Path:        dst,
+		Digest:      "sha256:" + hex.EncodeToString(h.Sum(nil)),
+		ContentType: resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// ArchiveFormat returns the archive format of the file by the name, or by the content type if the name has no known extension.
+// It returns empty if the file is not an archive.
+func ArchiveFormat(name, contentType string) string {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(strings.ToLower(name), "."+ext) {
+			return ext
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return archiveTypes[mediaType]
+}
+
+// isDocument reports whether the file is a JSON document by the name or the content type.
+func isDocument(name, contentType string) bool {
+	if strings.HasSuffix(strings.ToLower(name), ".json") {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Extract extracts the archive in the format returned by ArchiveFormat to dir.
+// WithMaxSize limits the total size of the extracted files, and WithMaxFiles limits the number of them.
+func Extract(format, src, dir string, opts ...Option) error {
+	o := newOptions(opts)
+	d, ok := getter.LimitedDecompressors(o.maxFiles, o.maxSize)[format]
+	if !ok {
+		return oops.Code("download_error").In("download").Errorf("unsupported archive format: %s", format)
+	}
+	if err := d.Decompress(dir, src, true, 0022); err != nil {
+		return oops.Code("download_error").In("download").With("src", src).Wrapf(err, "failed to extract the archive")
+	}
+	return nil
+}
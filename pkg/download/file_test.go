@@ -0,0 +1,146 @@
+package download_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestIsFile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/vex":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		case "/archive":
+			w.Header().Set("Content-Type", "application/gzip")
+		case "/org/repo":
+			w.Header().Set("Content-Type", "text/html")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	tests := []struct {
+		name   string
+		rawurl string
+		want   bool
+	}{
+		{
+			name:   "document by extension",
+			rawurl: "https://example.com/vex/product.openvex.json",
+			want:   true,
+		},
+		{
+			name:   "archive by extension",
+			rawurl: "https://github.com/org/repo/releases/download/v1.0.0/vex.tar.gz",
+			want:   true,
+		},
+		{
+			name:   "document by content type",
+			rawurl: ts.URL + "/vex",
+			want:   true,
+		},
+		{
+			name:   "archive by content type",
+			rawurl: ts.URL + "/archive",
+			want:   true,
+		},
+		{
+			name:   "repository with .git",
+			rawurl: "https://example.com/org/repo.git",
+		},
+		{
+			name:   "repository on forges",
+			rawurl: "https://github.com/org/repo",
+		},
+		{
+			name:   "document page on forges",
+			rawurl: "https://github.com/org/repo/blob/main/vex.json",
+		},
+		{
+			name:   "document page in a GitLab subgroup",
+			rawurl: "https://gitlab.com/group/subgroup/repo/-/blob/main/vex.json",
+		},
+		{
+			name:   "raw document on forges",
+			rawurl: "https://github.com/org/repo/raw/main/vex.json",
+			want:   true,
+		},
+		{
+			name:   "raw document in a GitLab subgroup",
+			rawurl: "https://gitlab.com/group/subgroup/repo/-/raw/main/vex.json",
+			want:   true,
+		},
+		{
+			name:   "document page with raw=true",
+			rawurl: "https://github.com/org/repo/blob/main/vex.json?raw=true",
+			want:   true,
+		},
+		{
+			name:   "archive on forges",
+			rawurl: "https://codeberg.org/org/repo/archive/v1.0.0.tar.gz",
+			want:   true,
+		},
+		{
+			name:   "repository with web page",
+			rawurl: ts.URL + "/org/repo",
+		},
+		{
+			name:   "not found",
+			rawurl: ts.URL + "/missed",
+		},
+		{
+			name:   "ssh",
+			rawurl: "ssh://git@example.com/org/vex.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, download.IsFile(context.Background(), tt.rawurl))
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	content := []byte(`{"statements": []}`)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openvex.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(ts.Close)
+
+	dst := filepath.Join(t.TempDir(), "dir", "openvex.json")
+	got, err := download.Get(context.Background(), ts.URL+"/openvex.json", dst)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256(content)
+	require.Equal(t, &download.File{
+		Path:        dst,
+		Digest:      "sha256:" + hex.EncodeToString(sum[:]),
+		ContentType: "application/json",
+	}, got)
+	b, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	require.Equal(t, content, b)
+
+	_, err = download.Get(context.Background(), ts.URL+"/missed.json", dst)
+	require.ErrorContains(t, err, "failed to get: 404 Not Found")
+
+	_, err = download.Get(context.Background(), ts.URL+"/openvex.json", dst, download.WithMaxSize(int64(len(content)-1)))
+	require.ErrorIs(t, err, download.ErrTooLarge)
+}
@@ -0,0 +1,358 @@
+package download
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/sideband"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/utils/ioutil"
+	"github.com/samber/oops"
+)
+
+// abbrevHash matches abbreviated commit hashes.
+var abbrevHash = regexp.MustCompile(`^[0-9a-f]{4,39}$`)
+
+// ErrUnsupported is returned by Sparse when the server doesn't support partial fetches of the ref.
+var ErrUnsupported = errors.New("partial fetch not supported")
+
+// Sparse fetches only the commit at the ref without checking out the working tree,
+// and writes the files and directories for which match returns true to dst.
+// The ref is a branch, a tag, a full commit hash, or empty for the default branch.
+// HEAD and the "origin" remote are kept in dst so that it can be read as a clone.
+//
+// Unlike Download, the history is not fetched even for commits, and the commit is fetched without blobs
+// as with "git clone --filter=blob:none --depth=1". Only the blobs of matched files are fetched afterwards,
+// which matters for large repositories. ErrUnsupported is returned if the server doesn't support
+// shallow fetches, object filters, or fetching objects by hash, or if the ref is an abbreviated commit hash.
+//
+// WithMaxSize limits both the transferred bytes, stopping the fetch once over it, and the written files.
+// WithMaxFiles limits the written files.
+func Sparse(ctx context.Context, repoURL, ref, dst string, match func(path string) bool, opts ...Option) error {
+	slog.Info("Fetching...", slog.String("url", redactURL(repoURL)), slog.String("ref", ref))
+	errBuilder := oops.Code("download_error").In("download").With("url", redactURL(repoURL)).With("ref", ref).With("dst", dst)
+	o := newOptions(opts)
+	auth, err := o.auth.method(repoURL)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to set up authentication")
+	}
+
+	repo, _, err := initRepo(dst, repoURL)
+	if err != nil {
+		return errBuilder.Wrap(err)
+	}
+
+	// The commit and its trees
+	t := &transfer{maxSize: o.maxSize}
+	var hash plumbing.Hash
+	err = uploadPack(ctx, repo, repoURL, auth, t, func(adv *packp.AdvRefs) (*packp.UploadPackRequest, error) {
+		for _, c := range []capability.Capability{capability.Shallow, capability.Filter, capability.AllowReachableSHA1InWant} {
+			if !adv.Capabilities.Supports(c) {
+				return nil, oops.With("capability", c).Wrapf(ErrUnsupported, "capability not advertised")
+			}
+		}
+		if hash, err = resolveRef(adv, ref); err != nil {
+			return nil, oops.Wrapf(err, "failed to resolve the ref")
+		}
+		req := newUploadPackRequest(adv, hash)
+		req.Depth = packp.DepthCommits(1)
+		req.Filter = packp.FilterBlobNone()
+		return req, errors.Join(req.Capabilities.Set(capability.Shallow), req.Capabilities.Set(capability.Filter))
+	})
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to fetch the commit")
+	}
+	fetchHead := plumbing.NewRemoteHEADReferenceName(git.DefaultRemoteName)
+	if err = repo.Storer.SetReference(plumbing.NewHashReference(fetchHead, hash)); err != nil {
+		return errBuilder.Wrapf(err, "failed to set the fetched reference")
+	}
+	commit, err := peel(repo, hash)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to get the commit")
+	}
+
+	// The blobs of matched files
+	blobs, err := matchedBlobs(commit, match)
+	if err != nil {
+		return errBuilder.With("commit", commit.Hash.String()).Wrap(err)
+	}
+	if len(blobs) > 0 {
+		err = uploadPack(ctx, repo, repoURL, auth, t, func(adv *packp.AdvRefs) (*packp.UploadPackRequest, error) {
+			return newUploadPackRequest(adv, blobs...), nil
+		})
+		if err != nil {
+			return errBuilder.With("commit", commit.Hash.String()).Wrapf(err, "failed to fetch the files")
+		}
+	}
+
+	if err = checkout(repo, repo, commit, dst, match, o.limits()); err != nil {
+		return errBuilder.With("commit", commit.Hash.String()).Wrap(err)
+	}
+	return nil
+}
+
+// uploadPack requests a pack built from the advertised references by newReq, and stores the received objects to repo.
+// As "git fetch" does, shallow commits sent by the server are recorded.
+// The response is read through t, which stops the transfer once it's over the size limit.
+func uploadPack(ctx context.Context, repo *git.Repository, repoURL string, auth transport.AuthMethod, t *transfer,
+	newReq func(adv *packp.AdvRefs) (*packp.UploadPackRequest, error)) (err error) {
+	ep, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return oops.Wrapf(err, "invalid repository URL")
+	}
+	c, err := client.NewClient(ep)
+	if err != nil {
+		return oops.Wrapf(err, "unsupported protocol")
+	}
+	s, err := c.NewUploadPackSession(ep, auth)
+	if err != nil {
+		return oops.Wrapf(err, "failed to connect")
+	}
+	defer ioutil.CheckClose(s, &err)
+
+	adv, err := s.AdvertisedReferencesContext(ctx)
+	if err != nil {
+		return oops.Wrapf(err, "failed to get references")
+	}
+	req, err := newReq(adv)
+	if err != nil {
+		return err
+	}
+	resp, err := s.UploadPack(ctx, req)
+	if err != nil {
+		return oops.Wrapf(err, "failed to request objects")
+	}
+	defer ioutil.CheckClose(resp, &err)
+
+	if len(resp.Shallows) > 0 {
+		if err = repo.Storer.SetShallow(resp.Shallows); err != nil {
+			return oops.Wrapf(err, "failed to set shallow commits")
+		}
+	}
+	err = packfile.UpdateObjectStorage(repo.Storer, demux(req.Capabilities, t.reader(resp)))
+	if t.exceeded() {
+		return tooLarge(t.size, t.maxSize) // The error of the reader may be dropped or not wrapped
+	} else if err != nil {
+		return oops.Wrapf(err, "failed to store objects")
+	}
+	return nil
+}
+
+// newUploadPackRequest returns the request for the objects without progress messages.
+func newUploadPackRequest(adv *packp.AdvRefs, wants ...plumbing.Hash) *packp.UploadPackRequest {
+	req := packp.NewUploadPackRequestFromCapabilities(adv.Capabilities)
+	req.Wants = wants
+	if adv.Capabilities.Supports(capability.NoProgress) {
+		_ = req.Capabilities.Set(capability.NoProgress)
+	}
+	return req
+}
+
+// demux extracts the pack from the side-band channel if it's used.
+func demux(caps *capability.List, r io.Reader) io.Reader {
+	switch {
+	case caps.Supports(capability.Sideband64k):
+		return sideband.NewDemuxer(sideband.Sideband64k, r)
+	case caps.Supports(capability.Sideband):
+		return sideband.NewDemuxer(sideband.Sideband, r)
+	}
+	return r
+}
+
+// initRepo initializes the repository in dir with the "origin" remote.
+func initRepo(dir, repoURL string) (*git.Repository, *git.Remote, error) {
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "failed to initialize the repository")
+	}
+	remote, err := repo.CreateRemote(&config.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{repoURL},
+	})
+	if err != nil {
+		return nil, nil, oops.Wrapf(err, "failed to create the remote")
+	}
+	return repo, remote, nil
+}
+
+// checkout detaches HEAD of repo at the commit as "git checkout" does,
+// and writes the matched files of the commit read from src to dir within the limits.
+func checkout(repo, src *git.Repository, commit *object.Commit, dir string, match func(path string) bool, l *limits) error {
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, commit.Hash)); err != nil {
+		return oops.Wrapf(err, "failed to set HEAD")
+	}
+	if err := writeTree(src, commit, dir, match, l); err != nil {
+		return oops.Wrapf(err, "failed to write files")
+	}
+	return nil
+}
+
+// resolveRef returns the object the ref points to in the advertised references.
+// Branches take precedence over tags with the same name, as in go-getter.
+func resolveRef(adv *packp.AdvRefs, ref string) (plumbing.Hash, error) {
+	switch {
+	case ref == "" && adv.Head != nil:
+		return *adv.Head, nil
+	case ref == "":
+		return plumbing.ZeroHash, oops.Errorf("HEAD not advertised")
+	case len(ref) == 40 && plumbing.IsHash(ref):
+		return plumbing.NewHash(ref), nil
+	}
+
+	for _, name := range []plumbing.ReferenceName{plumbing.NewBranchReferenceName(ref), plumbing.NewTagReferenceName(ref)} {
+		if hash, ok := adv.References[name.String()]; ok {
+			return hash, nil
+		}
+	}
+	if abbrevHash.MatchString(ref) {
+		// Only a clone can resolve abbreviated hashes
+		return plumbing.ZeroHash, oops.With("ref", ref).Wrapf(ErrUnsupported, "abbreviated commit hash")
+	}
+	return plumbing.ZeroHash, oops.Errorf("reference not found: %s", ref)
+}
+
+// peel returns the commit the hash points to, dereferencing annotated tags.
+func peel(repo *git.Repository, hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := repo.TagObject(hash); err == nil {
+		return tag.Commit()
+	}
+	return repo.CommitObject(hash)
+}
+
+// matchedBlobs returns the blobs of the matched files in the commit, which may not be stored yet.
+func matchedBlobs(commit *object.Commit, match func(path string) bool) ([]plumbing.Hash, error) {
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to get the tree")
+	}
+
+	var blobs []plumbing.Hash
+	seen := map[plumbing.Hash]bool{}
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			return blobs, nil
+		} else if err != nil {
+			return nil, oops.Wrapf(err, "failed to walk the tree")
+		}
+		if !entry.Mode.IsFile() || !match(name) || seen[entry.Hash] {
+			continue
+		}
+		seen[entry.Hash] = true
+		blobs = append(blobs, entry.Hash)
+	}
+}
+
+// writeTree writes the matched entries of the commit to dir.
+// Matched symbolic links and submodules are rejected, and files are counted against the limits before written.
+func writeTree(repo *git.Repository, commit *object.Commit, dir string, match func(path string) bool, l *limits) error {
+	tree, err := commit.Tree()
+	if err != nil {
+		return oops.Wrapf(err, "failed to get the tree")
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return oops.Wrapf(err, "failed to walk the tree")
+		}
+
+		if !filepath.IsLocal(name) {
+			return oops.With("path", name).Errorf("invalid path in the tree")
+		} else if !match(name) {
+			continue
+		}
+
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		switch entry.Mode {
+		case filemode.Dir:
+			if err = os.MkdirAll(filePath, 0755); err != nil {
+				return oops.With("path", name).Wrapf(err, "failed to create a directory")
+			}
+		case filemode.Regular, filemode.Deprecated, filemode.Executable:
+			blob, err := repo.BlobObject(entry.Hash)
+			if err != nil {
+				return oops.With("path", name).Wrapf(err, "failed to get the file")
+			}
+			if err = l.add(blob.Size); err != nil {
+				return oops.With("path", name).Wrap(err)
+			}
+			if err = writeBlob(blob, filePath); err != nil {
+				return oops.With("path", name).Wrapf(err, "failed to write the file")
+			}
+		case filemode.Symlink:
+			return rejectSymlink(repo, name, entry.Hash)
+		default:
+			return oops.Code("irregular_file_rejected").With("path", name).With("mode", entry.Mode.String()).
+				Errorf("irregular files are not allowed")
+		}
+	}
+}
+
+// rejectSymlink returns the error for the matched symbolic link, depending on whether it resolves outside the repository.
+func rejectSymlink(repo *git.Repository, name string, hash plumbing.Hash) error {
+	errBuilder := oops.With("path", name)
+	blob, err := repo.BlobObject(hash)
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to get the symbolic link")
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to read the symbolic link")
+	}
+	defer r.Close()
+	target, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return errBuilder.Wrapf(err, "failed to read the symbolic link")
+	}
+
+	resolved := path.Join(path.Dir(name), string(target))
+	if path.IsAbs(string(target)) || !filepath.IsLocal(filepath.FromSlash(resolved)) {
+		return errBuilder.Code("path_escape_rejected").With("target", string(target)).
+			Errorf("symbolic link resolves outside the repository")
+	}
+	return errBuilder.Code("symlink_rejected").Errorf("symbolic links are not allowed")
+}
+
+func writeBlob(blob *object.Blob, filePath string) error {
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,301 @@
+package download_test
+
+import (
+	"cmp"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sosedoff/gitkit"
+	"github.com/stretchr/testify/require"
+
+	"github.com/aquasecurity/vexhub-crawler/pkg/download"
+)
+
+func TestSparse(t *testing.T) {
+	tests := []struct {
+		name      string
+		repo      string
+		ref       string
+		opts      []download.Option
+		want      string // Ref of the expected commit
+		wantErr   string
+		wantFiles map[string]string
+	}{
+		{
+			name: "happy path with the default branch",
+			wantFiles: map[string]string{
+				".vex/openvex.json":     "main",
+				"sub/.vex/openvex.json": "main",
+			},
+		},
+		{
+			name: "happy path with a branch",
+			ref:  "dev",
+			wantFiles: map[string]string{
+				".vex/openvex.json":     "dev",
+				"sub/.vex/openvex.json": "main",
+			},
+		},
+		{
+			name: "happy path with an annotated tag",
+			ref:  "v1.0.0",
+			wantFiles: map[string]string{
+				".vex/openvex.json":     "main",
+				"sub/.vex/openvex.json": "main",
+			},
+		},
+		{
+			name: "happy path with a commit",
+			ref:  "{{dev}}",
+			want: "dev",
+			wantFiles: map[string]string{
+				".vex/openvex.json":     "dev",
+				"sub/.vex/openvex.json": "main",
+			},
+		},
+		{
+			name:    "sad path with missed ref",
+			ref:     "missed",
+			wantErr: "reference not found: missed",
+		},
+		{
+			name:    "sad path with an abbreviated commit",
+			ref:     "{{dev:7}}",
+			wantErr: "partial fetch not supported",
+		},
+		{
+			name:    "sad path without partial fetches",
+			repo:    "plain",
+			wantErr: "partial fetch not supported",
+		},
+		{
+			name:    "sad path over the size limit",
+			opts:    []download.Option{download.WithMaxSize(100)},
+			wantErr: "size limit exceeded",
+		},
+		{
+			name:    "sad path over the file limit",
+			opts:    []download.Option{download.WithMaxFiles(1)},
+			wantErr: "file limit exceeded",
+		},
+	}
+
+	server, commits := newServer(t, false)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repoName := cmp.Or(tt.repo, "repo")
+			ref := strings.NewReplacer("{{dev}}", commits["dev"].String(), "{{dev:7}}", commits["dev"].String()[:7]).Replace(tt.ref)
+			dst := filepath.Join(t.TempDir(), "repo")
+			err := download.Sparse(context.Background(), server.URL+"/"+repoName+".git", ref, dst, func(path string) bool {
+				return path == ".vex" || path == "sub" || strings.HasSuffix(path, ".json")
+			}, tt.opts...)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			for name, want := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(dst, name))
+				require.NoError(t, err)
+				require.Equal(t, want, string(got))
+			}
+			require.NoFileExists(t, filepath.Join(dst, "main.go"))
+			require.NoFileExists(t, filepath.Join(dst, "sub", "main.go"))
+
+			// HEAD is readable as in a clone
+			repo, err := git.PlainOpen(dst)
+			require.NoError(t, err)
+			head, err := repo.Head()
+			require.NoError(t, err)
+			want := cmp.Or(tt.want, tt.ref)
+			if want == "" || want == "v1.0.0" {
+				want = "main"
+			}
+			require.Equal(t, commits[want], head.Hash())
+
+			// Blobs of unmatched files are not fetched
+			commit, err := repo.CommitObject(head.Hash())
+			require.NoError(t, err)
+			tree, err := commit.Tree()
+			require.NoError(t, err)
+			entry, err := tree.FindEntry("main.go")
+			require.NoError(t, err)
+			require.ErrorIs(t, repo.Storer.HasEncodedObject(entry.Hash), plumbing.ErrObjectNotFound)
+		})
+	}
+}
+
+// newServer serves a repository with "main" and "dev" branches, and an annotated tag on "main",
+// as "repo" supporting partial fetches and as "plain" without them.
+// If auth is true, the server requires the username "git" and the password "secret".
+func newServer(t *testing.T, auth bool) (*httptest.Server, map[string]plumbing.Hash) {
+	wtDir := t.TempDir()
+	r, err := git.PlainInit(wtDir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	signature := &object.Signature{
+		Name:  "Test",
+		Email: "test@example.com",
+		When:  time.Now(),
+	}
+	commit := func(content string) plumbing.Hash {
+		for _, name := range []string{".vex/openvex.json", "sub/.vex/openvex.json", "main.go", "sub/main.go"} {
+			if content != "main" && strings.HasPrefix(name, "sub/") {
+				continue
+			}
+			filePath := filepath.Join(wtDir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+			data := content
+			if strings.HasSuffix(name, ".go") {
+				data = "package " + content
+			}
+			require.NoError(t, os.WriteFile(filePath, []byte(data), 0644))
+		}
+		_, err := wt.Add(".")
+		require.NoError(t, err)
+		hash, err := wt.Commit(content, &git.CommitOptions{Author: signature})
+		require.NoError(t, err)
+		return hash
+	}
+
+	commits := map[string]plumbing.Hash{}
+	commits["main"] = commit("main")
+	require.NoError(t, r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName("main"), commits["main"])))
+	require.NoError(t, r.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))))
+	_, err = r.CreateTag("v1.0.0", commits["main"], &git.CreateTagOptions{Tagger: signature, Message: "v1.0.0"})
+	require.NoError(t, err)
+
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("dev"), Create: true}))
+	commits["dev"] = commit("dev")
+	require.NoError(t, wt.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("main")}))
+
+	// "repo" supports partial fetches as GitHub does, and "plain" doesn't
+	bareDir := t.TempDir()
+	for _, name := range []string{"repo", "plain"} {
+		bare, err := git.PlainClone(filepath.Join(bareDir, name+".git"), true, &git.CloneOptions{URL: wtDir, Mirror: true})
+		require.NoError(t, err)
+		if name == "plain" {
+			continue
+		}
+		cfg, err := bare.Config()
+		require.NoError(t, err)
+		cfg.Raw.Section("uploadpack").SetOption("allowFilter", "true")
+		cfg.Raw.Section("uploadpack").SetOption("allowReachableSHA1InWant", "true")
+		require.NoError(t, bare.SetConfig(cfg))
+	}
+
+	service := gitkit.New(gitkit.Config{Dir: bareDir, Auth: auth})
+	service.AuthFunc = func(cred gitkit.Credential, _ *gitkit.Request) (bool, error) {
+		return cred.Username == "git" && cred.Password == "secret", nil
+	}
+	server := httptest.NewServer(service)
+	t.Cleanup(server.Close)
+	return server, commits
+}
+
+func TestCache_Sparse(t *testing.T) {
+	tests := []struct {
+		name    string
+		ref     string
+		opts    []download.Option
+		want    string // Ref of the expected commit
+		wantErr string
+	}{
+		{
+			name: "happy path with the default branch",
+			want: "main",
+		},
+		{
+			name: "happy path with a branch",
+			ref:  "dev",
+			want: "dev",
+		},
+		{
+			name: "happy path with an annotated tag",
+			ref:  "v1.0.0",
+			want: "main",
+		},
+		{
+			name: "happy path with an abbreviated commit",
+			ref:  "{{dev}}",
+			want: "dev",
+		},
+		{
+			name:    "sad path with missed ref",
+			ref:     "missed",
+			wantErr: "reference not found: missed",
+		},
+		{
+			name: "happy path with the mirror over the size limit",
+			opts: []download.Option{download.WithMaxSize(100)},
+			want: "main",
+		},
+		{
+			name:    "sad path over the size limit",
+			opts:    []download.Option{download.WithMaxSize(5)},
+			wantErr: "size limit exceeded",
+		},
+		{
+			name:    "sad path over the file limit",
+			opts:    []download.Option{download.WithMaxFiles(1)},
+			wantErr: "file limit exceeded",
+		},
+	}
+
+	server, commits := newServer(t, false)
+	cache := download.NewCache(t.TempDir(), 0)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref := strings.ReplaceAll(tt.ref, "{{dev}}", commits["dev"].String()[:7])
+			dst := filepath.Join(t.TempDir(), "repo")
+			err := cache.Sparse(context.Background(), "repo", server.URL+"/repo.git", ref, dst, func(path string) bool {
+				return strings.HasSuffix(path, ".json")
+			}, tt.opts...)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := os.ReadFile(filepath.Join(dst, ".vex", "openvex.json"))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(got))
+			require.NoFileExists(t, filepath.Join(dst, "main.go"))
+
+			repo, err := git.PlainOpen(dst)
+			require.NoError(t, err)
+			head, err := repo.Head()
+			require.NoError(t, err)
+			require.Equal(t, commits[tt.want], head.Hash())
+		})
+	}
+}
+
+func TestCache_Evict(t *testing.T) {
+	server, _ := newServer(t, false)
+	cacheDir := t.TempDir()
+	cache := download.NewCache(cacheDir, 1) // Only the last mirror is kept
+
+	for _, key := range []string{"first", "second"} {
+		dst := filepath.Join(t.TempDir(), "repo")
+		err := cache.Sparse(context.Background(), key, server.URL+"/repo.git", "", dst, func(string) bool { return false })
+		require.NoError(t, err)
+	}
+
+	mirrors, err := filepath.Glob(filepath.Join(cacheDir, "*.git"))
+	require.NoError(t, err)
+	require.Len(t, mirrors, 1)
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package download
+
+import "errors"
+
+var errLocked = errors.New("locked")
+
+// lockFile is a no-op where flock(2) is not available. Mirrors are still locked within the process.
+func lockFile(string, bool) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,36 @@
+//go:build unix
+
+package download
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/samber/oops"
+)
+
+var errLocked = errors.New("locked")
+
+// lockFile takes the exclusive lock of the file, which is released when the process exits as well.
+func lockFile(path string, wait bool) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, oops.With("path", path).Wrapf(err, "failed to open the lock file")
+	}
+	how := syscall.LOCK_EX
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+	if err = syscall.Flock(int(f.Fd()), how); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLocked
+		}
+		return nil, oops.With("path", path).Wrapf(err, "failed to lock")
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,56 @@
+package download
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/samber/oops"
+)
+
+// Open opens the metadata at the HTTP(S) URL, or at the local path for mirrors on the filesystem.
+// Local paths can be either plain paths or "file://" URLs.
+func Open(ctx context.Context, rawurl string) (io.ReadCloser, error) {
+	errBuilder := oops.Code("download_error").In("download").With("url", rawurl)
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse url")
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "file", "":
+		f, err := os.Open(u.Path)
+		if err != nil {
+			return nil, errBuilder.Wrapf(err, "failed to open file")
+		}
+		return f, nil
+	default:
+		return nil, errBuilder.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	resp, err := get(ctx, http.MethodGet, rawurl)
+	if err != nil {
+		return nil, errBuilder.Wrap(err)
+	}
+	return resp.Body, nil
+}
+
+// get sends the request and returns the response if the status is 200 OK.
+func get(ctx context.Context, method, rawurl string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, nil)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to create request")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, oops.Wrapf(err, "failed to get")
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, oops.Errorf("failed to get: %s", resp.Status)
+	}
+	return resp, nil
+}
@@ -0,0 +1,121 @@
+package download
+
+import (
+	"errors"
+	"io"
+
+	"github.com/samber/oops"
+)
+
+var (
+	ErrTooLarge     = errors.New("size limit exceeded")
+	ErrTooManyFiles = errors.New("file limit exceeded")
+)
+
+type options struct {
+	auth     *Auth
+	maxSize  int64
+	maxFiles int
+}
+
+type Option func(*options)
+
+// WithAuth authenticates to repositories with the credentials.
+func WithAuth(auth *Auth) Option {
+	return func(o *options) {
+		o.auth = auth
+	}
+}
+
+// WithMaxSize limits the bytes downloaded and written, such as the size of a repository or an archive and its files.
+// Zero means no limit.
+func WithMaxSize(size int64) Option {
+	return func(o *options) {
+		o.maxSize = size
+	}
+}
+
+// WithMaxFiles limits the number of files written from a repository or an archive. Zero means no limit.
+func WithMaxFiles(n int) Option {
+	return func(o *options) {
+		o.maxFiles = n
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// limits tracks the bytes and files written against the limits.
+type limits struct {
+	maxSize  int64
+	maxFiles int
+	size     int64
+	files    int
+}
+
+func (o options) limits() *limits {
+	return &limits{
+		maxSize:  o.maxSize,
+		maxFiles: o.maxFiles,
+	}
+}
+
+// add counts a file of the size, and returns an error if it exceeds the limits.
+func (l *limits) add(size int64) error {
+	l.size += size
+	l.files++
+	if l.maxSize > 0 && l.size > l.maxSize {
+		return tooLarge(l.size, l.maxSize)
+	} else if l.maxFiles > 0 && l.files > l.maxFiles {
+		return oops.Code("file_limit_exceeded").With("max_files", l.maxFiles).Wrap(ErrTooManyFiles)
+	}
+	return nil
+}
+
+// transfer counts the bytes read through its readers, and fails the read once they exceed maxSize. Zero means no limit.
+type transfer struct {
+	maxSize int64
+	size    int64
+}
+
+func (t *transfer) reader(r io.Reader) io.Reader {
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := r.Read(p)
+		t.size += int64(n)
+		if t.exceeded() {
+			return n, tooLarge(t.size, t.maxSize)
+		}
+		return n, err
+	})
+}
+
+func (t *transfer) exceeded() bool {
+	return t.maxSize > 0 && t.size > t.maxSize
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
+
+// checkSize returns ErrTooLarge if the files in dir exceed maxSize bytes. Zero means no limit.
+func checkSize(dir string, maxSize int64) error {
+	if maxSize <= 0 {
+		return nil
+	}
+	size, err := dirSize(dir)
+	if err != nil {
+		return oops.Wrapf(err, "failed to get the size")
+	} else if size > maxSize {
+		return tooLarge(size, maxSize)
+	}
+	return nil
+}
+
+func tooLarge(size, maxSize int64) error {
+	return oops.Code("size_limit_exceeded").With("size", size).With("max_size", maxSize).Wrap(ErrTooLarge)
+}
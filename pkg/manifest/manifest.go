@@ -15,8 +15,10 @@ type Manifest struct {
 }
 
 type Source struct {
-	Path string
-	URL  string
+	Path   string
+	URL    string
+	Digest string `json:",omitempty"` // Digest of the OCI image, artifact or downloaded file the document came from
+	Commit string `json:",omitempty"` // Commit of the source repository the document came from
 }
 
 func Write(filePath string, m Manifest) error {
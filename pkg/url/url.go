@@ -4,16 +4,27 @@ import (
 	"fmt"
 	"net/url"
 	"path"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/samber/oops"
 )
 
+// commitIDRegex matches strings likely to be commit IDs, as go-getter does.
+var commitIDRegex = regexp.MustCompile("^[0-9a-fA-F]{7,40}$")
+
 type URL struct {
 	*url.URL
 	depth   int
 	ref     string
 	subdirs string
+
+	// fallbackSubdirs are tried in order if subdirs doesn't exist in the repository
+	fallbackSubdirs []string
+
+	// digest is the digest of the artifact the repository was found in, such as an OCI image
+	digest string
 }
 
 // Parse parses rawurl into a URL structure.
@@ -47,6 +58,87 @@ func Parse(rawurl string) (*URL, error) {
 	return u, nil
 }
 
+// ParseVCS parses the `vcs_url` qualifier of PURLs in the SPDX format,
+// `<vcs_tool>+<transport>://<host_name>[/<path_to_repository>][@<revision_tag_or_branch>][#<sub_path>]`.
+// cf. https://spdx.github.io/spdx-spec/v2.3/package-information/#771-description
+func ParseVCS(rawurl string) (*URL, error) {
+	errBuilder := oops.Code("url_parse_error").In("url").With("url", rawurl)
+
+	s := rawurl
+	if tool, after, found := strings.Cut(s, "+"); found && !strings.Contains(tool, "/") {
+		if tool != "git" {
+			return nil, errBuilder.Errorf("unsupported version control system: %s", tool)
+		}
+		s = after
+	}
+	s, subdirs, _ := strings.Cut(s, "#")
+
+	parsed, err := url.Parse(s)
+	if err != nil {
+		return nil, errBuilder.Wrapf(err, "failed to parse URL")
+	}
+
+	// "@" in the path separates the revision, while "@" in the authority is for user info
+	var ref string
+	if i := strings.LastIndex(parsed.Path, "@"); i > strings.LastIndex(parsed.Path, "/") {
+		parsed.Path, ref = parsed.Path[:i], parsed.Path[i+1:]
+		parsed.RawPath = ""
+	}
+
+	u, err := Parse(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	if ref != "" {
+		u.SetRef(ref)
+	}
+	if subdirs = strings.Trim(subdirs, "/"); subdirs != "" {
+		u.SetSubdirs(subdirs)
+	}
+	return u, nil
+}
+
+// forges are hosts serving repositories at /<owner>/<repo> along with their releases and archives.
+// GitLab also nests repositories in subgroups.
+var forges = map[string]struct{}{
+	"github.com":    {},
+	"gitlab.com":    {},
+	"bitbucket.org": {},
+	"codeberg.org":  {},
+}
+
+// ParseForge returns the repository of URLs on well-known forges, such as release assets and archives,
+// e.g. https://github.com/<owner>/<repo>/releases/download/<tag>/<asset> => https://github.com/<owner>/<repo>.
+// On GitLab, the repository path ends before "/-/", e.g. https://gitlab.com/<group>/<subgroup>/<repo>/-/archive/...
+// It reports false if the URL is not on the forges.
+func ParseForge(rawurl string) (*URL, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, false
+	}
+	host := strings.TrimPrefix(u.Host, "www.")
+	if _, ok := forges[host]; !ok || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, false
+	}
+
+	p := strings.Trim(u.Path, "/")
+	if host == "gitlab.com" {
+		p, _, _ = strings.Cut(p, "/-/")
+	}
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 || slices.Contains(parts, "") {
+		return nil, false
+	} else if host != "gitlab.com" {
+		parts = parts[:2]
+	}
+	parts[len(parts)-1] = strings.TrimSuffix(parts[len(parts)-1], ".git")
+	repo, err := Parse("https://" + host + "/" + strings.Join(parts, "/"))
+	if err != nil {
+		return nil, false
+	}
+	return repo, true
+}
+
 func parseGitHubURL(u *URL) {
 	// Split the path
 	parts := strings.Split(u.Path, "/")
@@ -62,6 +154,10 @@ func parseGitHubURL(u *URL) {
 	}
 
 	if len(parts) < 6 {
+		if u.ref != "" {
+			// e.g. /<owner>/<repo>/tree/<ref>
+			u.Path = path.Join(parts[1], parts[2])
+		}
 		return
 	}
 
@@ -81,6 +177,32 @@ func (u *URL) Subdirs() string {
 	return u.subdirs
 }
 
+// SetFallbackSubdirs sets subdirectories to be used if Subdirs doesn't exist in the repository.
+func (u *URL) SetFallbackSubdirs(dirs ...string) {
+	u.fallbackSubdirs = dirs
+}
+
+func (u *URL) FallbackSubdirs() []string {
+	return u.fallbackSubdirs
+}
+
+func (u *URL) SetRef(ref string) {
+	u.ref = ref
+}
+
+func (u *URL) Ref() string {
+	return u.ref
+}
+
+// SetDigest sets the digest of the artifact the repository was found in.
+func (u *URL) SetDigest(digest string) {
+	u.digest = digest
+}
+
+func (u *URL) Digest() string {
+	return u.digest
+}
+
 func (u *URL) String() string {
 	return u.URL.String()
 }
@@ -101,9 +223,14 @@ func (u *URL) GetterString() string {
 		uu.Path += ".git"
 	}
 
-	// Add depth=1 query parameter
+	// Add depth=1 query parameter.
+	// Shallow clones can check out only branches and tags, so commits need the full history.
+	depth := u.depth
+	if commitIDRegex.MatchString(u.ref) {
+		depth = 0
+	}
 	q := uu.Query()
-	q.Add("depth", fmt.Sprint(u.depth))
+	q.Add("depth", fmt.Sprint(depth))
 	if u.ref != "" {
 		q.Add("ref", u.ref)
 	}
@@ -111,3 +238,35 @@ func (u *URL) GetterString() string {
 
 	return uu.String()
 }
+
+// Canonical returns the repository URL without user info, parameters, the ".git" suffix and the trailing slash,
+// so that URLs pointing to the same repository are equal, e.g. for caching.
+func (u *URL) Canonical() string {
+	uu := url.URL{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   strings.ToLower(u.Host),
+		Path:   strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git"),
+	}
+	if !strings.HasPrefix(uu.Path, "/") {
+		uu.Path = "/" + uu.Path
+	}
+	return uu.String()
+}
+
+// Sanitized returns the URL string without user info, so that credentials don't leak into logs and manifests.
+func (u *URL) Sanitized() string {
+	uu := *u.URL
+	uu.User = nil
+	return uu.String()
+}
+
+// GitString returns URL string to fetch the repository with Git, without go-getter specific parameters.
+func (u *URL) GitString() string {
+	uu := *u.URL
+	if !strings.HasSuffix(uu.Path, ".git") {
+		uu.Path += ".git"
+	}
+	uu.RawPath = ""
+	uu.RawQuery = ""
+	return uu.String()
+}
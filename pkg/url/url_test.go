@@ -27,6 +27,16 @@ func TestURL_GetterString(t *testing.T) {
 			want:        "git::https://github.com/user/repo.git?depth=1&ref=main",
 			wantSubDirs: "subfolder/subfolder2",
 		},
+		{
+			name:   "happy path - GitHub URL with tree and no subfolder",
+			rawURL: "https://github.com/user/repo/tree/v1.2.3",
+			want:   "git::https://github.com/user/repo.git?depth=1&ref=v1.2.3",
+		},
+		{
+			name:   "happy path - GitHub URL with commit",
+			rawURL: "https://github.com/user/repo/tree/5a7fdc5a72af148c82128cf07e3b668ffd347454",
+			want:   "git::https://github.com/user/repo.git?depth=0&ref=5a7fdc5a72af148c82128cf07e3b668ffd347454",
+		},
 		{
 			name:        "happy path - GitHub URL with subdirs",
 			rawURL:      "https://github.com/hashicorp/go-getter.git//testdata",
@@ -64,3 +74,168 @@ func TestURL_GetterString(t *testing.T) {
 		})
 	}
 }
+
+func TestURL_GitString(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+	}{
+		{
+			name:   "GitHub URL with tree",
+			rawURL: "https://github.com/user/repo/tree/main/subfolder",
+			want:   "https://github.com/user/repo.git",
+		},
+		{
+			name:   "URL with subdirs",
+			rawURL: "https://example.com/user/repo.git//testdata",
+			want:   "https://example.com/user/repo.git",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, u.GitString())
+		})
+	}
+}
+
+func TestURL_Canonical(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+	}{
+		{
+			name:   "GitHub URL with tree",
+			rawURL: "https://github.com/user/repo/tree/main/subfolder",
+			want:   "https://github.com/user/repo",
+		},
+		{
+			name:   "URL with user info, .git suffix and subdirs",
+			rawURL: "https://token@Example.com/user/repo.git//testdata",
+			want:   "https://example.com/user/repo",
+		},
+		{
+			name:   "URL with trailing slash",
+			rawURL: "https://gitlab.com/user/repo/",
+			want:   "https://gitlab.com/user/repo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.rawURL)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, u.Canonical())
+		})
+	}
+}
+
+func TestParseVCS(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawURL      string
+		want        string
+		wantRef     string
+		wantSubDirs string
+		wantErr     string
+	}{
+		{
+			name:   "happy path - git+https",
+			rawURL: "git+https://github.com/package-url/purl-spec.git",
+			want:   "https://github.com/package-url/purl-spec.git",
+		},
+		{
+			name:        "happy path - revision and subpath",
+			rawURL:      "git+https://github.com/package-url/purl-spec.git@244fd47e07d1004f0aed9c#docs/types",
+			want:        "https://github.com/package-url/purl-spec.git",
+			wantRef:     "244fd47e07d1004f0aed9c",
+			wantSubDirs: "docs/types",
+		},
+		{
+			name:    "happy path - ssh with user info",
+			rawURL:  "git+ssh://git@gitlab.example.com/group/repo.git@v1.0.0",
+			want:    "ssh://git@gitlab.example.com/group/repo.git",
+			wantRef: "v1.0.0",
+		},
+		{
+			name:   "happy path - without vcs tool",
+			rawURL: "https://bitbucket.org/owner/repo",
+			want:   "https://bitbucket.org/owner/repo",
+		},
+		{
+			name:    "sad path - unsupported vcs tool",
+			rawURL:  "hg+https://hg.example.com/repo",
+			wantErr: "unsupported version control system: hg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := url.ParseVCS(tt.rawURL)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got.String())
+			require.Equal(t, tt.wantRef, got.Ref())
+			require.Equal(t, tt.wantSubDirs, got.Subdirs())
+		})
+	}
+}
+
+func TestParseForge(t *testing.T) {
+	tests := []struct {
+		name   string
+		rawURL string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "GitHub release asset",
+			rawURL: "https://github.com/owner/repo/releases/download/v1.0.0/repo_linux_amd64.tar.gz",
+			want:   "https://github.com/owner/repo",
+			wantOK: true,
+		},
+		{
+			name:   "GitLab archive",
+			rawURL: "https://gitlab.com/owner/repo.git/-/archive/v1.0.0/repo-v1.0.0.tar.gz",
+			want:   "https://gitlab.com/owner/repo",
+			wantOK: true,
+		},
+		{
+			name:   "GitLab subgroup archive",
+			rawURL: "https://gitlab.com/group/subgroup/repo/-/archive/v1.0.0/repo-v1.0.0.tar.gz",
+			want:   "https://gitlab.com/group/subgroup/repo",
+			wantOK: true,
+		},
+		{
+			name:   "GitLab subgroup repository",
+			rawURL: "https://gitlab.com/group/subgroup/repo.git",
+			want:   "https://gitlab.com/group/subgroup/repo",
+			wantOK: true,
+		},
+		{
+			name:   "unknown host",
+			rawURL: "https://www.openssl.org/source/openssl-3.3.2.tar.gz",
+		},
+		{
+			name:   "without repository",
+			rawURL: "https://github.com/owner",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := url.ParseForge(tt.rawURL)
+			require.Equal(t, tt.wantOK, ok)
+			if ok {
+				require.Equal(t, tt.want, got.String())
+			}
+		})
+	}
+}